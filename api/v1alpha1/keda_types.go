@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -43,11 +46,32 @@ const (
 	ConditionReasonVerification        = ConditionReason("Verification")
 	ConditionReasonInitialized         = ConditionReason("Initialized")
 	ConditionReasonDeletionErr         = ConditionReason("DeletionErr")
-
-	ConditionTypeInstalled = ConditionType("Installed")
-	OperatorLogLevelDebug  = OperatorLogLevel("debug")
-	OperatorLogLevelInfo   = OperatorLogLevel("info")
-	OperatorLogLevelError  = OperatorLogLevel("error")
+	ConditionReasonNamespaceErr        = ConditionReason("NamespaceErr")
+	ConditionReasonDryRunCompleted     = ConditionReason("DryRunCompleted")
+	ConditionReasonDryRunErr           = ConditionReason("DryRunErr")
+	ConditionReasonPaused              = ConditionReason("Paused")
+	ConditionReasonDriftDetected       = ConditionReason("DriftDetected")
+	ConditionReasonMonitoringErr       = ConditionReason("MonitoringErr")
+	ConditionReasonNetworkPolicyErr    = ConditionReason("NetworkPolicyErr")
+	ConditionReasonPodSecurityErr      = ConditionReason("PodSecurityErr")
+	ConditionReasonCertificatesErr     = ConditionReason("CertificatesErr")
+	ConditionReasonMissingPermissions  = ConditionReason("MissingPermissions")
+	ConditionReasonPreflightPassed     = ConditionReason("PreflightPassed")
+	ConditionReasonPreflightFailed     = ConditionReason("PreflightFailed")
+	ConditionReasonMisplacedInstance   = ConditionReason("MisplacedInstance")
+	ConditionReasonTriggerAuthErr      = ConditionReason("TriggerAuthenticationErr")
+	ConditionReasonPodIdentityErr      = ConditionReason("PodIdentityErr")
+	ConditionReasonNetworkErr          = ConditionReason("NetworkErr")
+	ConditionReasonRenderErr           = ConditionReason("RenderErr")
+
+	ConditionTypeInstalled     = ConditionType("Installed")
+	ConditionTypeDryRun        = ConditionType("DryRun")
+	ConditionTypePaused        = ConditionType("Paused")
+	ConditionTypeDriftDetected = ConditionType("DriftDetected")
+	ConditionTypePreflight     = ConditionType("Preflight")
+	OperatorLogLevelDebug      = OperatorLogLevel("debug")
+	OperatorLogLevelInfo       = OperatorLogLevel("info")
+	OperatorLogLevelError      = OperatorLogLevel("error")
 
 	LogFormatJSON    = LogFormat("json")
 	LogFormatConsole = LogFormat("console")
@@ -64,6 +88,18 @@ const (
 
 	Finalizer = "keda-manager.kyma-project.io/deletion-hook"
 
+	// DryRunAnnotation set to "true" makes the manager compute changes
+	// of keda components instead of applying them
+	DryRunAnnotation = "keda-manager.kyma-project.io/dry-run"
+
+	// MetricsServerEnvAnnotation keeps env of the metrics server given in
+	// newer API versions, if it differs from env of the operator
+	MetricsServerEnvAnnotation = "keda-manager.kyma-project.io/metrics-server-env"
+	// MetricsServerEnvChecksumAnnotation keeps the checksum of the operator
+	// env the metrics server env was given with; the metrics server env is
+	// outdated once the operator env is changed in this API version
+	MetricsServerEnvChecksumAnnotation = "keda-manager.kyma-project.io/metrics-server-env-checksum"
+
 	zapLogLevel           = "--zap-log-level"
 	zapEncoder            = "--zap-encoder"
 	zapTimeEncoding       = "--zap-time-encoding"
@@ -196,11 +232,236 @@ type Resources struct {
 	MetricsServer *corev1.ResourceRequirements `json:"metricServer,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=annotations;monitors
+type MonitoringMode string
+
+const (
+	// MonitoringModeAnnotations adds prometheus.io annotations to keda pods
+	MonitoringModeAnnotations = MonitoringMode("annotations")
+	// MonitoringModeMonitors creates ServiceMonitor and PodMonitor objects
+	MonitoringModeMonitors = MonitoringMode("monitors")
+)
+
+type Monitoring struct {
+	// Enabled exposes metrics of the operator and the metrics server for Prometheus
+	Enabled bool `json:"enabled,omitempty"`
+	// Mode of exposing metrics; monitors are used if the monitoring.coreos.com
+	// CRDs exist on the cluster and annotations otherwise, if not set
+	Mode *MonitoringMode `json:"mode,omitempty"`
+	// Interval of scraping metrics by monitors
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ms|s|m|h))+$`
+	Interval *string `json:"interval,omitempty"`
+}
+
+type NetworkPolicyPort struct {
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP
+	// +kubebuilder:default=TCP
+	Protocol *corev1.Protocol `json:"protocol,omitempty"`
+}
+
+type NetworkPolicyEgress struct {
+	// CIDRs keda components can connect to; all destinations are allowed if not set
+	CIDRs []string `json:"cidrs,omitempty"`
+	// Ports keda components can connect to; all ports are allowed if not set
+	Ports []NetworkPolicyPort `json:"ports,omitempty"`
+}
+
+type NetworkPolicyCfg struct {
+	// Enabled creates network policies for the operator and the metrics server
+	Enabled bool `json:"enabled,omitempty"`
+	// APIServerCIDRs the kube-apiserver connects to the metrics server from;
+	// the metrics server accepts connections from everywhere if not set
+	APIServerCIDRs []string `json:"apiServerCIDRs,omitempty"`
+	// Egress of keda components to scaler backends, in addition to the
+	// kube-apiserver and DNS; egress is not restricted if not set
+	Egress []NetworkPolicyEgress `json:"egress,omitempty"`
+}
+
+// SecurityCfg hardens pods of the operator and the metrics server; pods
+// have to satisfy the "restricted" Pod Security Standard
+type SecurityCfg struct {
+	// +kubebuilder:validation:Minimum=1
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	FSGroup *int64 `json:"fsGroup,omitempty"`
+	// ReadOnlyRootFilesystem of keda containers; writable directories
+	// of the metrics server are mounted as empty dirs
+	ReadOnlyRootFilesystem *bool                  `json:"readOnlyRootFilesystem,omitempty"`
+	SeccompProfile         *corev1.SeccompProfile `json:"seccompProfile,omitempty"`
+	PriorityClassName      *string                `json:"priorityClassName,omitempty"`
+	// AutomountServiceAccountToken of keda pods; keda components use the
+	// token to reach the kube-apiserver
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+}
+
+type CertificatesCfg struct {
+	// Enabled makes the manager provide the serving certificate of the
+	// metrics server, so the APIService verifies it instead of skipping
+	// the TLS verification
+	Enabled bool `json:"enabled,omitempty"`
+	// CertManager delegates issuing certificates to cert-manager, if its
+	// CRDs exist on the cluster
+	CertManager bool `json:"certManager,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=none;azure;azure-workload;aws-eks;aws-kiam;gcp
+type PodIdentityProvider string
+
+const (
+	PodIdentityProviderNone          = PodIdentityProvider("none")
+	PodIdentityProviderAzure         = PodIdentityProvider("azure")
+	PodIdentityProviderAzureWorkload = PodIdentityProvider("azure-workload")
+	PodIdentityProviderAWSEKS        = PodIdentityProvider("aws-eks")
+	PodIdentityProviderAWSKiam       = PodIdentityProvider("aws-kiam")
+	PodIdentityProviderGCP           = PodIdentityProvider("gcp")
+)
+
+type TriggerAuthSecretTargetRef struct {
+	// Parameter of the scaler the secret value is passed as
+	Parameter string `json:"parameter"`
+	// Name of the secret in the namespace of keda components
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type TriggerAuthEnv struct {
+	// Parameter of the scaler the env value is passed as
+	Parameter string `json:"parameter"`
+	// Name of the env of the scale target container
+	Name          string  `json:"name"`
+	ContainerName *string `json:"containerName,omitempty"`
+}
+
+type TriggerAuthPodIdentity struct {
+	Provider   PodIdentityProvider `json:"provider"`
+	IdentityID *string             `json:"identityId,omitempty"`
+}
+
+// TriggerAuthentication is a ClusterTriggerAuthentication managed by the manager
+type TriggerAuthentication struct {
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`
+	Name            string                       `json:"name"`
+	SecretTargetRef []TriggerAuthSecretTargetRef `json:"secretTargetRef,omitempty"`
+	Env             []TriggerAuthEnv             `json:"env,omitempty"`
+	PodIdentity     *TriggerAuthPodIdentity      `json:"podIdentity,omitempty"`
+}
+
+type AzurePodIdentityCfg struct {
+	// Identity of the AzureIdentityBinding selecting the operator pods
+	// +kubebuilder:validation:MinLength=1
+	Identity string `json:"identity"`
+}
+
+type AzureWorkloadIdentityCfg struct {
+	// ClientID of the Azure AD application or the user-assigned identity
+	// +kubebuilder:validation:MinLength=1
+	ClientID string `json:"clientId"`
+	// TenantID of the identity; the tenant of the cluster is used if not set
+	TenantID *string `json:"tenantId,omitempty"`
+	// TokenExpiration of the projected service account token in seconds
+	// +kubebuilder:validation:Minimum=3600
+	// +kubebuilder:validation:Maximum=86400
+	TokenExpiration *int64 `json:"tokenExpiration,omitempty"`
+}
+
+type AWSEKSIdentityCfg struct {
+	// RoleArn of the IAM role assumed by the operator (IRSA)
+	// +kubebuilder:validation:Pattern=`^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$`
+	RoleArn string `json:"roleArn"`
+}
+
+type GCPIdentityCfg struct {
+	// ServiceAccount is the email of the Google service account the
+	// operator impersonates with GKE workload identity
+	// +kubebuilder:validation:Pattern=`^[^@]+@[^@]+\.iam\.gserviceaccount\.com$`
+	ServiceAccount string `json:"serviceAccount"`
+}
+
+// PodIdentityCfg configures the identity scalers authenticate with; the
+// settings of the selected provider are required, settings of other
+// providers are rejected
+// +kubebuilder:validation:XValidation:rule="self.provider == 'azure' ? has(self.azure) : !has(self.azure)",message="azure is required by, and only allowed with, the azure provider"
+// +kubebuilder:validation:XValidation:rule="self.provider == 'azure-workload' ? has(self.azureWorkload) : !has(self.azureWorkload)",message="azureWorkload is required by, and only allowed with, the azure-workload provider"
+// +kubebuilder:validation:XValidation:rule="self.provider == 'aws-eks' ? has(self.awsEks) : !has(self.awsEks)",message="awsEks is required by, and only allowed with, the aws-eks provider"
+// +kubebuilder:validation:XValidation:rule="self.provider == 'gcp' ? has(self.gcp) : !has(self.gcp)",message="gcp is required by, and only allowed with, the gcp provider"
+type PodIdentityCfg struct {
+	// +kubebuilder:validation:Enum=azure;azure-workload;aws-eks;gcp
+	Provider      PodIdentityProvider       `json:"provider"`
+	Azure         *AzurePodIdentityCfg      `json:"azure,omitempty"`
+	AzureWorkload *AzureWorkloadIdentityCfg `json:"azureWorkload,omitempty"`
+	AWSEKS        *AWSEKSIdentityCfg        `json:"awsEks,omitempty"`
+	GCP           *GCPIdentityCfg           `json:"gcp,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=ConfigMap;Secret
+type CABundleKind string
+
+const (
+	CABundleKindConfigMap = CABundleKind("ConfigMap")
+	CABundleKindSecret    = CABundleKind("Secret")
+)
+
+// CABundleRef references PEM encoded certificates of additional CAs
+type CABundleRef struct {
+	// +kubebuilder:default=ConfigMap
+	Kind CABundleKind `json:"kind,omitempty"`
+	// Name of the object in the namespace of keda components
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// +kubebuilder:default=ca.crt
+	Key string `json:"key,omitempty"`
+}
+
+// NetworkCfg configures connections of keda components to scaler backends
+type NetworkCfg struct {
+	HTTPProxy  *string `json:"httpProxy,omitempty"`
+	HTTPSProxy *string `json:"httpsProxy,omitempty"`
+	// NoProxy lists hosts connected to directly; in-cluster services and
+	// the kube-apiserver are always connected to directly
+	NoProxy *string `json:"noProxy,omitempty"`
+	// CABundle is trusted by keda components in addition to system CAs;
+	// pods are rolled out again once its content changes
+	CABundle *CABundleRef `json:"caBundle,omitempty"`
+}
+
 // KedaSpec defines the desired state of Keda
+// +kubebuilder:validation:XValidation:rule="!has(self.triggerAuthentications) || self.triggerAuthentications.all(a, !has(a.podIdentity) || a.podIdentity.provider == 'none' || (has(self.podIdentity) && a.podIdentity.provider == self.podIdentity.provider))",message="trigger authentications may only use the pod identity provider of the operator"
 type KedaSpec struct {
 	Logging   *LoggingCfg `json:"logging,omitempty"`
 	Resources *Resources  `json:"resources,omitempty"`
 	Env       EnvVars     `json:"env,omitempty"`
+	// TargetNamespace is the namespace keda components are installed in;
+	// the manager's default target namespace is used if not set
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	TargetNamespace *string `json:"targetNamespace,omitempty"`
+	// Paused stops the manager from updating keda components, e.g. to
+	// hot-fix them; components are reconciled again once it is cleared
+	Paused bool `json:"paused,omitempty"`
+	// Monitoring configures scraping of keda components by Prometheus
+	Monitoring *Monitoring `json:"monitoring,omitempty"`
+	// NetworkPolicy configures network policies of keda components
+	NetworkPolicy *NetworkPolicyCfg `json:"networkPolicy,omitempty"`
+	// Security hardens pods of keda components
+	Security *SecurityCfg `json:"security,omitempty"`
+	// Certificates configures TLS certificates of the metrics server
+	Certificates *CertificatesCfg `json:"certificates,omitempty"`
+	// TriggerAuthentications are ClusterTriggerAuthentications shared by
+	// scalers of all namespaces; they are removed once they are not listed
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=100
+	TriggerAuthentications []TriggerAuthentication `json:"triggerAuthentications,omitempty"`
+	// PodIdentity configures the pod identity of the operator
+	PodIdentity *PodIdentityCfg `json:"podIdentity,omitempty"`
+	// Network configures proxies and CAs of keda components
+	Network *NetworkCfg `json:"network,omitempty"`
 }
 
 type EnvVars []corev1.EnvVar
@@ -261,8 +522,20 @@ func (v *EnvVars) Sanitize() {
 	*v = append(*v, required...)
 }
 
+// Checksum identifies the env; empty env has the same checksum as no env
+func (v EnvVars) Checksum() string {
+	if len(v) == 0 {
+		v = nil
+	}
+	// env vars always marshal
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:storageversion
 //+kubebuilder:printcolumn:name="generation",type="integer",JSONPath=".metadata.generation"
 //+kubebuilder:printcolumn:name="age",type="date",JSONPath=".metadata.creationTimestamp"
 //+kubebuilder:printcolumn:name="state",type="string",JSONPath=".status.state"
@@ -312,9 +585,59 @@ func (k *Keda) UpdateStateProcessing(c ConditionType, r ConditionReason, msg str
 	meta.SetStatusCondition(&k.Status.Conditions, condition)
 }
 
+// UpdateCondition sets given condition without changing the state
+func (k *Keda) UpdateCondition(c ConditionType, s metav1.ConditionStatus, r ConditionReason, msg string) {
+	condition := metav1.Condition{
+		Type:               string(c),
+		Status:             s,
+		LastTransitionTime: metav1.Now(),
+		Reason:             string(r),
+		Message:            msg,
+	}
+	meta.SetStatusCondition(&k.Status.Conditions, condition)
+}
+
+func (k *Keda) IsDryRun() bool {
+	return k.GetAnnotations()[DryRunAnnotation] == "true"
+}
+
+// MetricsServerEnv returns env of the metrics server; the env is shared
+// with the operator unless it was given separately in a newer API version,
+// and the operator env has not been changed in this API version since
+func (k *Keda) MetricsServerEnv() (EnvVars, error) {
+	value, found := k.GetAnnotations()[MetricsServerEnvAnnotation]
+	if !found || k.GetAnnotations()[MetricsServerEnvChecksumAnnotation] != k.Spec.Env.Checksum() {
+		return k.Spec.Env, nil
+	}
+
+	var result EnvVars
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", MetricsServerEnvAnnotation, err)
+	}
+	return result, nil
+}
+
+type CertificatesStatus struct {
+	// Issuer of the serving certificate, keda-manager or cert-manager
+	Issuer string `json:"issuer"`
+	// NotAfter is the expiry time of the serving certificate
+	NotAfter *metav1.Time `json:"notAfter,omitempty"`
+}
+
 type Status struct {
-	State      string             `json:"state"`
-	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	State        string              `json:"state"`
+	Conditions   []metav1.Condition  `json:"conditions,omitempty"`
+	Certificates *CertificatesStatus `json:"certificates,omitempty"`
+	// ObservedGeneration is the generation of the spec keda components were last ready with
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// TargetNamespace is the namespace keda components were last applied in
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// MonitorsCreated is set once monitors of keda components are created,
+	// and cleared once they are removed
+	MonitorsCreated bool `json:"monitorsCreated,omitempty"`
+	// NetworkPoliciesCreated is set once network policies of keda components
+	// are created, and cleared once they are removed
+	NetworkPoliciesCreated bool `json:"networkPoliciesCreated,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -180,3 +180,61 @@ func Test_string(t *testing.T) {
 		})
 	}
 }
+
+func TestKeda_MetricsServerEnv(t *testing.T) {
+	operatorEnv := EnvVars{{Name: "OPERATOR", Value: "test"}}
+	metricsServerEnv := `[{"name":"METRICS_SERVER","value":"test"}]`
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name: "shared env",
+			want: "OPERATOR",
+		},
+		{
+			name: "metrics server env",
+			annotations: map[string]string{
+				MetricsServerEnvAnnotation:         metricsServerEnv,
+				MetricsServerEnvChecksumAnnotation: operatorEnv.Checksum(),
+			},
+			want: "METRICS_SERVER",
+		},
+		{
+			name: "operator env changed since",
+			annotations: map[string]string{
+				MetricsServerEnvAnnotation:         metricsServerEnv,
+				MetricsServerEnvChecksumAnnotation: EnvVars(nil).Checksum(),
+			},
+			want: "OPERATOR",
+		},
+		{
+			name: "invalid metrics server env",
+			annotations: map[string]string{
+				MetricsServerEnvAnnotation:         "invalid",
+				MetricsServerEnvChecksumAnnotation: operatorEnv.Checksum(),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := Keda{Spec: KedaSpec{Env: operatorEnv}}
+			k.SetAnnotations(tt.annotations)
+
+			got, err := k.MetricsServerEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MetricsServerEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != 1 || got[0].Name != tt.want {
+				t.Errorf("MetricsServerEnv() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
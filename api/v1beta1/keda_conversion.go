@@ -0,0 +1,240 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var (
+	metricsServerLogLevels = map[MetricsServerLogLevel]v1alpha1.MetricsServerLogLevel{
+		MetricsServerLogLevelInfo:  v1alpha1.MetricsServerLogLevelInfo,
+		MetricsServerLogLevelDebug: v1alpha1.MetricsServerLogLevelDebug,
+	}
+)
+
+func metricsServerLogLevelTo(level *MetricsServerLogLevel) (*v1alpha1.MetricsServerLogLevel, error) {
+	if level == nil {
+		return nil, nil
+	}
+	result, found := metricsServerLogLevels[*level]
+	if !found {
+		return nil, fmt.Errorf("invalid metrics server log level: %s", *level)
+	}
+	return &result, nil
+}
+
+func metricsServerLogLevelFrom(level *v1alpha1.MetricsServerLogLevel) (*MetricsServerLogLevel, error) {
+	if level == nil {
+		return nil, nil
+	}
+	for result, hubLevel := range metricsServerLogLevels {
+		if hubLevel == *level {
+			return &result, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid metrics server log level: %s", *level)
+}
+
+func envVars(env []corev1.EnvVar) v1alpha1.EnvVars {
+	if env == nil {
+		return nil
+	}
+	return v1alpha1.EnvVars(env)
+}
+
+// withoutAnnotations returns a copy of given annotations without given keys
+func withoutAnnotations(annotations map[string]string, keys ...string) map[string]string {
+	var result map[string]string
+	for k, v := range annotations {
+		if contains(keys, k) {
+			continue
+		}
+		if result == nil {
+			result = map[string]string{}
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// ConvertTo converts this Keda to the hub version (v1alpha1)
+func (src *Keda) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.Keda)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.ObjectMeta.Annotations = withoutAnnotations(dst.Annotations,
+		v1alpha1.MetricsServerEnvAnnotation,
+		v1alpha1.MetricsServerEnvChecksumAnnotation,
+	)
+
+	operator := src.Spec.Operator
+	if operator == nil {
+		operator = &OperatorCfg{}
+	}
+	metricsServer := src.Spec.MetricsServer
+	if metricsServer == nil {
+		metricsServer = &MetricsServerCfg{}
+	}
+
+	var logging v1alpha1.LoggingCfg
+	if operator.Logging != nil {
+		logging.Operator = &v1alpha1.LoggingOperatorCfg{
+			Level:        operator.Logging.Level,
+			Format:       operator.Logging.Format,
+			TimeEncoding: operator.Logging.TimeEncoding,
+		}
+	}
+	if metricsServer.Logging != nil {
+		level, err := metricsServerLogLevelTo(metricsServer.Logging.Level)
+		if err != nil {
+			return err
+		}
+		logging.MetricsServer = &v1alpha1.LoggingMetricsSrvCfg{Level: level}
+	}
+	if logging.Operator != nil || logging.MetricsServer != nil {
+		dst.Spec.Logging = &logging
+	}
+
+	if operator.Resources != nil || metricsServer.Resources != nil {
+		dst.Spec.Resources = &v1alpha1.Resources{
+			Operator:      operator.Resources,
+			MetricsServer: metricsServer.Resources,
+		}
+	}
+
+	// the hub shares env between components, the metrics server env
+	// is kept in the annotation if it differs; nil and empty env are
+	// the same, and annotations of equal envs are dropped above
+	dst.Spec.Env = envVars(operator.Env)
+	if !equality.Semantic.DeepEqual(operator.Env, metricsServer.Env) {
+		env, err := json.Marshal(metricsServer.Env)
+		if err != nil {
+			return err
+		}
+		if dst.Annotations == nil {
+			dst.Annotations = map[string]string{}
+		}
+		dst.Annotations[v1alpha1.MetricsServerEnvAnnotation] = string(env)
+		dst.Annotations[v1alpha1.MetricsServerEnvChecksumAnnotation] = dst.Spec.Env.Checksum()
+	}
+
+	dst.Spec.TargetNamespace = src.Spec.TargetNamespace
+	dst.Spec.Paused = src.Spec.Paused
+	dst.Spec.Monitoring = src.Spec.Monitoring
+	dst.Spec.NetworkPolicy = src.Spec.NetworkPolicy
+	dst.Spec.Security = src.Spec.Security
+	dst.Spec.Certificates = src.Spec.Certificates
+	dst.Spec.TriggerAuthentications = src.Spec.TriggerAuthentications
+	dst.Spec.PodIdentity = src.Spec.PodIdentity
+	dst.Spec.Network = src.Spec.Network
+
+	dst.Status = v1alpha1.Status{
+		State:                  src.Status.State,
+		Conditions:             src.Status.Conditions,
+		Certificates:           src.Status.Certificates,
+		ObservedGeneration:     src.Status.ObservedGeneration,
+		TargetNamespace:        src.Status.TargetNamespace,
+		MonitorsCreated:        src.Status.MonitorsCreated,
+		NetworkPoliciesCreated: src.Status.NetworkPoliciesCreated,
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v1alpha1) to this version
+func (dst *Keda) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.Keda)
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.ObjectMeta.Annotations = withoutAnnotations(dst.Annotations,
+		v1alpha1.MetricsServerEnvAnnotation,
+		v1alpha1.MetricsServerEnvChecksumAnnotation,
+	)
+
+	metricsServerEnv, err := src.MetricsServerEnv()
+	if err != nil {
+		return err
+	}
+
+	var operator OperatorCfg
+	var metricsServer MetricsServerCfg
+	if logging := src.Spec.Logging; logging != nil {
+		if logging.Operator != nil {
+			operator.Logging = &OperatorLogging{
+				Level:        logging.Operator.Level,
+				Format:       logging.Operator.Format,
+				TimeEncoding: logging.Operator.TimeEncoding,
+			}
+		}
+		if logging.MetricsServer != nil {
+			level, err := metricsServerLogLevelFrom(logging.MetricsServer.Level)
+			if err != nil {
+				return err
+			}
+			metricsServer.Logging = &MetricsServerLogging{Level: level}
+		}
+	}
+	if resources := src.Spec.Resources; resources != nil {
+		operator.Resources = resources.Operator
+		metricsServer.Resources = resources.MetricsServer
+	}
+	operator.Env = src.Spec.Env
+	metricsServer.Env = metricsServerEnv
+
+	dst.Spec = KedaSpec{
+		TargetNamespace: src.Spec.TargetNamespace,
+		Paused:          src.Spec.Paused,
+		Monitoring:      src.Spec.Monitoring,
+		NetworkPolicy:   src.Spec.NetworkPolicy,
+		Security:        src.Spec.Security,
+		Certificates:    src.Spec.Certificates,
+	}
+	dst.Spec.TriggerAuthentications = src.Spec.TriggerAuthentications
+	dst.Spec.PodIdentity = src.Spec.PodIdentity
+	dst.Spec.Network = src.Spec.Network
+	if !reflect.DeepEqual(operator, OperatorCfg{}) {
+		dst.Spec.Operator = &operator
+	}
+	if !reflect.DeepEqual(metricsServer, MetricsServerCfg{}) {
+		dst.Spec.MetricsServer = &metricsServer
+	}
+
+	dst.Status = Status{
+		State:                  src.Status.State,
+		Conditions:             src.Status.Conditions,
+		Certificates:           src.Status.Certificates,
+		ObservedGeneration:     src.Status.ObservedGeneration,
+		TargetNamespace:        src.Status.TargetNamespace,
+		MonitorsCreated:        src.Status.MonitorsCreated,
+		NetworkPoliciesCreated: src.Status.NetworkPoliciesCreated,
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	testOperatorLogLevelDebug   = v1alpha1.OperatorLogLevelDebug
+	testMetricsServerLogLevel   = v1alpha1.MetricsServerLogLevelDebug
+	testMetricsServerLogLevelV1 = MetricsServerLogLevelDebug
+	testResources               = &corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+	}
+	testProxy = "http://proxy.example.com:3128"
+	testEnv   = []corev1.EnvVar{{Name: "KEDA_HTTP_DEFAULT_TIMEOUT", Value: "5000"}}
+)
+
+func Test_ConvertFrom_ConvertTo(t *testing.T) {
+	tests := []struct {
+		name string
+		hub  v1alpha1.Keda
+	}{
+		{
+			name: "empty",
+			hub: v1alpha1.Keda{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "kyma-system"},
+			},
+		},
+		{
+			name: "all components configured",
+			hub: v1alpha1.Keda{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Namespace:   "kyma-system",
+					Annotations: map[string]string{v1alpha1.DryRunAnnotation: "true"},
+				},
+				Spec: v1alpha1.KedaSpec{
+					Logging: &v1alpha1.LoggingCfg{
+						Operator:      &v1alpha1.LoggingOperatorCfg{Level: &testOperatorLogLevelDebug},
+						MetricsServer: &v1alpha1.LoggingMetricsSrvCfg{Level: &testMetricsServerLogLevel},
+					},
+					Resources: &v1alpha1.Resources{
+						Operator:      testResources,
+						MetricsServer: testResources,
+					},
+					Env:        testEnv,
+					Paused:     true,
+					Monitoring: &v1alpha1.Monitoring{Enabled: true},
+					TriggerAuthentications: []v1alpha1.TriggerAuthentication{
+						{
+							Name:        "azure",
+							PodIdentity: &v1alpha1.TriggerAuthPodIdentity{Provider: v1alpha1.PodIdentityProviderAzureWorkload},
+						},
+					},
+					PodIdentity: &v1alpha1.PodIdentityCfg{
+						Provider:      v1alpha1.PodIdentityProviderAzureWorkload,
+						AzureWorkload: &v1alpha1.AzureWorkloadIdentityCfg{ClientID: "0000-1111"},
+					},
+					Network: &v1alpha1.NetworkCfg{
+						HTTPSProxy: &testProxy,
+						CABundle:   &v1alpha1.CABundleRef{Kind: v1alpha1.CABundleKindSecret, Name: "ca", Key: "ca.crt"},
+					},
+				},
+				Status: v1alpha1.Status{
+					State:              v1alpha1.StateReady,
+					ObservedGeneration: 2,
+				},
+			},
+		},
+		{
+			name: "metrics server env",
+			hub: v1alpha1.Keda{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "kyma-system",
+					Annotations: map[string]string{
+						v1alpha1.MetricsServerEnvAnnotation:         `[{"name":"TEST","value":"test"}]`,
+						v1alpha1.MetricsServerEnvChecksumAnnotation: v1alpha1.EnvVars(testEnv).Checksum(),
+					},
+				},
+				Spec: v1alpha1.KedaSpec{Env: testEnv},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var spoke Keda
+			if err := spoke.ConvertFrom(&tt.hub); err != nil {
+				t.Fatalf("ConvertFrom() error = %v", err)
+			}
+
+			var hub v1alpha1.Keda
+			if err := spoke.ConvertTo(&hub); err != nil {
+				t.Fatalf("ConvertTo() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.hub, hub) {
+				t.Errorf("round trip = %+v, want %+v", hub, tt.hub)
+			}
+		})
+	}
+}
+
+func Test_ConvertFrom(t *testing.T) {
+	hub := v1alpha1.Keda{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				v1alpha1.MetricsServerEnvAnnotation:         `[{"name":"TEST","value":"test"}]`,
+				v1alpha1.MetricsServerEnvChecksumAnnotation: v1alpha1.EnvVars(testEnv).Checksum(),
+			},
+		},
+		Spec: v1alpha1.KedaSpec{
+			Logging: &v1alpha1.LoggingCfg{
+				MetricsServer: &v1alpha1.LoggingMetricsSrvCfg{Level: &testMetricsServerLogLevel},
+			},
+			Env: testEnv,
+		},
+	}
+
+	var spoke Keda
+	if err := spoke.ConvertFrom(&hub); err != nil {
+		t.Fatalf("ConvertFrom() error = %v", err)
+	}
+
+	want := KedaSpec{
+		Operator: &OperatorCfg{Env: testEnv},
+		MetricsServer: &MetricsServerCfg{
+			Logging: &MetricsServerLogging{Level: &testMetricsServerLogLevelV1},
+			Env:     []corev1.EnvVar{{Name: "TEST", Value: "test"}},
+		},
+	}
+	if !reflect.DeepEqual(want, spoke.Spec) {
+		t.Errorf("ConvertFrom() = %+v, want %+v", spoke.Spec, want)
+	}
+	if spoke.Annotations != nil {
+		t.Errorf("ConvertFrom() annotations = %v, want none", spoke.Annotations)
+	}
+}
+
+func Test_ConvertTo_invalidLogLevel(t *testing.T) {
+	level := MetricsServerLogLevel("trace")
+	spoke := Keda{
+		Spec: KedaSpec{
+			MetricsServer: &MetricsServerCfg{
+				Logging: &MetricsServerLogging{Level: &level},
+			},
+		},
+	}
+
+	var hub v1alpha1.Keda
+	if err := spoke.ConvertTo(&hub); err == nil {
+		t.Errorf("ConvertTo() error = nil, want error")
+	}
+}
+
+func Test_ConvertTo_equalEnv(t *testing.T) {
+	tests := []struct {
+		name          string
+		operator      []corev1.EnvVar
+		metricsServer []corev1.EnvVar
+	}{
+		{
+			name:          "nil and empty env",
+			metricsServer: []corev1.EnvVar{},
+		},
+		{
+			name:          "same env",
+			operator:      testEnv,
+			metricsServer: testEnv,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spoke := Keda{
+				ObjectMeta: metav1.ObjectMeta{
+					// the annotations are outdated
+					Annotations: map[string]string{
+						v1alpha1.MetricsServerEnvAnnotation:         `[{"name":"TEST","value":"test"}]`,
+						v1alpha1.MetricsServerEnvChecksumAnnotation: v1alpha1.EnvVars(tt.operator).Checksum(),
+					},
+				},
+				Spec: KedaSpec{
+					Operator:      &OperatorCfg{Env: tt.operator},
+					MetricsServer: &MetricsServerCfg{Env: tt.metricsServer},
+				},
+			}
+
+			var hub v1alpha1.Keda
+			if err := spoke.ConvertTo(&hub); err != nil {
+				t.Fatalf("ConvertTo() error = %v", err)
+			}
+			if hub.Annotations != nil {
+				t.Errorf("ConvertTo() annotations = %v, want none", hub.Annotations)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:validation:Enum=info;debug
+type MetricsServerLogLevel string
+
+const (
+	MetricsServerLogLevelInfo  = MetricsServerLogLevel("info")
+	MetricsServerLogLevelDebug = MetricsServerLogLevel("debug")
+)
+
+type OperatorLogging struct {
+	Level        *v1alpha1.OperatorLogLevel `json:"level,omitempty"`
+	Format       *v1alpha1.LogFormat        `json:"format,omitempty"`
+	TimeEncoding *v1alpha1.LogTimeEncoding  `json:"timeEncoding,omitempty"`
+}
+
+type MetricsServerLogging struct {
+	Level *MetricsServerLogLevel `json:"level,omitempty"`
+}
+
+// OperatorCfg configures the keda operator
+type OperatorCfg struct {
+	Logging   *OperatorLogging             `json:"logging,omitempty"`
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	Env       []corev1.EnvVar              `json:"env,omitempty"`
+}
+
+// MetricsServerCfg configures the keda metrics server
+type MetricsServerCfg struct {
+	Logging   *MetricsServerLogging        `json:"logging,omitempty"`
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+	Env       []corev1.EnvVar              `json:"env,omitempty"`
+}
+
+// KedaSpec defines the desired state of Keda; blocks not related to
+// a single component are the same as in v1alpha1
+// +kubebuilder:validation:XValidation:rule="!has(self.triggerAuthentications) || self.triggerAuthentications.all(a, !has(a.podIdentity) || a.podIdentity.provider == 'none' || (has(self.podIdentity) && a.podIdentity.provider == self.podIdentity.provider))",message="trigger authentications may only use the pod identity provider of the operator"
+type KedaSpec struct {
+	Operator      *OperatorCfg      `json:"operator,omitempty"`
+	MetricsServer *MetricsServerCfg `json:"metricsServer,omitempty"`
+	// TargetNamespace is the namespace keda components are installed in;
+	// the manager's default target namespace is used if not set
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	TargetNamespace *string `json:"targetNamespace,omitempty"`
+	// Paused stops the manager from updating keda components, e.g. to
+	// hot-fix them; components are reconciled again once it is cleared
+	Paused bool `json:"paused,omitempty"`
+	// Monitoring configures scraping of keda components by Prometheus
+	Monitoring *v1alpha1.Monitoring `json:"monitoring,omitempty"`
+	// NetworkPolicy configures network policies of keda components
+	NetworkPolicy *v1alpha1.NetworkPolicyCfg `json:"networkPolicy,omitempty"`
+	// Security hardens pods of keda components
+	Security *v1alpha1.SecurityCfg `json:"security,omitempty"`
+	// Certificates configures TLS certificates of the metrics server
+	Certificates *v1alpha1.CertificatesCfg `json:"certificates,omitempty"`
+	// TriggerAuthentications are ClusterTriggerAuthentications shared by
+	// scalers of all namespaces; they are removed once they are not listed
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=100
+	TriggerAuthentications []v1alpha1.TriggerAuthentication `json:"triggerAuthentications,omitempty"`
+	// PodIdentity configures the pod identity of the operator
+	PodIdentity *v1alpha1.PodIdentityCfg `json:"podIdentity,omitempty"`
+	// Network configures proxies and CAs of keda components
+	Network *v1alpha1.NetworkCfg `json:"network,omitempty"`
+}
+
+type Status struct {
+	State        string                       `json:"state"`
+	Conditions   []metav1.Condition           `json:"conditions,omitempty"`
+	Certificates *v1alpha1.CertificatesStatus `json:"certificates,omitempty"`
+	// ObservedGeneration is the generation of the spec keda components were last ready with
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// TargetNamespace is the namespace keda components were last applied in
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// MonitorsCreated is set once monitors of keda components are created,
+	// and cleared once they are removed
+	MonitorsCreated bool `json:"monitorsCreated,omitempty"`
+	// NetworkPoliciesCreated is set once network policies of keda components
+	// are created, and cleared once they are removed
+	NetworkPoliciesCreated bool `json:"networkPoliciesCreated,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="generation",type="integer",JSONPath=".metadata.generation"
+//+kubebuilder:printcolumn:name="age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:printcolumn:name="state",type="string",JSONPath=".status.state"
+
+// Keda is the Schema for the kedas API
+type Keda struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KedaSpec `json:"spec,omitempty"`
+	Status Status   `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// KedaList contains a list of Keda
+type KedaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Keda `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Keda{}, &KedaList{})
+}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Keda conversion webhook", func() {
+	It("should round-trip an instance through v1beta1", func() {
+		ctx := context.Background()
+		debug := v1beta1.MetricsServerLogLevelDebug
+
+		// the instance is paused, so that it is not reconciled
+		instance := v1beta1.Keda{
+			ObjectMeta: metav1.ObjectMeta{Name: "conversion", Namespace: "default"},
+			Spec: v1beta1.KedaSpec{
+				Operator: &v1beta1.OperatorCfg{
+					Env: []corev1.EnvVar{{Name: "operator-env", Value: "operator"}},
+				},
+				MetricsServer: &v1beta1.MetricsServerCfg{
+					Logging: &v1beta1.MetricsServerLogging{Level: &debug},
+					Env:     []corev1.EnvVar{{Name: "metrics-server-env", Value: "metrics-server"}},
+				},
+				Paused: true,
+			},
+		}
+		Expect(k8sClient.Create(ctx, &instance)).To(Succeed())
+		defer func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, &instance))).To(Succeed())
+		}()
+		key := client.ObjectKeyFromObject(&instance)
+
+		By("reading the stored version")
+		var hub v1alpha1.Keda
+		Expect(k8sClient.Get(ctx, key, &hub)).To(Succeed())
+		Expect(hub.Spec.Env).To(Equal(v1alpha1.EnvVars(instance.Spec.Operator.Env)))
+		Expect(hub.Spec.Logging.MetricsServer.Level).To(HaveValue(Equal(v1alpha1.MetricsServerLogLevelDebug)))
+		Expect(hub.MetricsServerEnv()).To(Equal(v1alpha1.EnvVars(instance.Spec.MetricsServer.Env)))
+
+		By("reading v1beta1 again")
+		var spoke v1beta1.Keda
+		Expect(k8sClient.Get(ctx, key, &spoke)).To(Succeed())
+		Expect(spoke.Spec).To(Equal(instance.Spec))
+		Expect(spoke.GetAnnotations()).NotTo(HaveKey(v1alpha1.MetricsServerEnvAnnotation))
+	})
+})
@@ -22,14 +22,18 @@ import (
 	"github.com/kyma-project/keda-manager/api/v1alpha1"
 	"github.com/kyma-project/keda-manager/pkg/reconciler"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -44,13 +48,16 @@ type KedaReconciler interface {
 // kedaReconciler reconciles a Keda object
 type kedaReconciler struct {
 	log *zap.SugaredLogger
+	// the maxConcurrentReconciles bounds instances reconciled at a time
+	maxConcurrentReconciles int
 	reconciler.Cfg
 	reconciler.K8s
 }
 
 func (r *kedaReconciler) mapFunction(object client.Object) []reconcile.Request {
+	// instances outside of the keda-manager namespace do not manage keda components
 	var kedas v1alpha1.KedaList
-	err := r.List(context.Background(), &kedas)
+	err := r.List(context.Background(), &kedas, client.InNamespace(r.KedaNamespace))
 
 	if apierrors.IsNotFound(err) {
 		return nil
@@ -71,6 +78,11 @@ func (r *kedaReconciler) mapFunction(object client.Object) []reconcile.Request {
 		return nil
 	}
 
+	// instance is paused, changes of its components are not reverted
+	if kedas.Items[0].Spec.Paused {
+		return nil
+	}
+
 	r.log.
 		With("name", object.GetName()).
 		With("ns", object.GetNamespace()).
@@ -90,6 +102,41 @@ func (r *kedaReconciler) mapFunction(object client.Object) []reconcile.Request {
 	}
 }
 
+// mapReferencedObj notifies instances whose keda components refer to the
+// changed Secret or ConfigMap
+func (r *kedaReconciler) mapReferencedObj(object client.Object) []reconcile.Request {
+	var kedas v1alpha1.KedaList
+	if err := r.List(context.Background(), &kedas, client.InNamespace(r.KedaNamespace)); err != nil {
+		r.log.Error(err)
+		return nil
+	}
+
+	var result []reconcile.Request
+	for i := range kedas.Items {
+		instance := &kedas.Items[i]
+		if !instance.GetDeletionTimestamp().IsZero() || instance.Spec.Paused {
+			continue
+		}
+		if !r.IsReferenced(instance, object) {
+			continue
+		}
+
+		r.log.
+			With("name", object.GetName()).
+			With("ns", object.GetNamespace()).
+			With("kedaName", instance.Name).
+			Debug("referenced object changed")
+
+		result = append(result, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Namespace: instance.Namespace,
+				Name:      instance.Name,
+			},
+		})
+	}
+	return result
+}
+
 var ommitStatusChanged = predicate.Or(
 	predicate.LabelChangedPredicate{},
 	predicate.AnnotationChangedPredicate{},
@@ -110,7 +157,8 @@ func (r *kedaReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	}
 
 	b := ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Keda{}, builder.WithPredicates(ommitStatusChanged))
+		For(&v1alpha1.Keda{}, builder.WithPredicates(ommitStatusChanged)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.maxConcurrentReconciles})
 
 	// create functtion to register wached objects
 	watchFn := func(u unstructured.Unstructured) {
@@ -131,6 +179,18 @@ func (r *kedaReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	// Secrets and ConfigMaps referenced by keda components are not labeled;
+	// they are watched in all namespaces, as instances may install keda
+	// components in any of them, but only their metadata is cached
+	for _, obj := range []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}} {
+		b = b.Watches(
+			&source.Kind{Type: obj},
+			handler.EnqueueRequestsFromMapFunc(r.mapReferencedObj),
+			builder.OnlyMetadata,
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{}),
+		)
+	}
+
 	return b.Complete(r)
 }
 
@@ -142,23 +202,32 @@ func (r *kedaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		}, client.IgnoreNotFound(err)
 	}
 
-	stateFSM := reconciler.NewFsm(r.log, r.Cfg, reconciler.K8s{
-		Client:        r.Client,
-		EventRecorder: r.EventRecorder,
-	})
+	log := r.log.With(
+		"name", req.Name,
+		"namespace", req.Namespace,
+		"reconcileID", uuid.NewUUID(),
+	)
+	stateFSM := reconciler.NewFsm(log, r.Cfg, r.K8s)
 	return stateFSM.Run(ctx, instance)
 }
 
-func NewKedaReconciler(c client.Client, r record.EventRecorder, log *zap.SugaredLogger, o []unstructured.Unstructured) KedaReconciler {
+func NewKedaReconciler(c client.Client, r record.EventRecorder, d discovery.DiscoveryInterface, log *zap.SugaredLogger, o []unstructured.Unstructured, render reconciler.RenderFunc, namespace, kedaNamespace string, dryRun bool, missingPermissions []string, maxConcurrentReconciles int) KedaReconciler {
 	return &kedaReconciler{
-		log: log,
+		log:                     log,
+		maxConcurrentReconciles: maxConcurrentReconciles,
 		Cfg: reconciler.Cfg{
-			Finalizer: v1alpha1.Finalizer,
-			Objs:      o,
+			Finalizer:          v1alpha1.Finalizer,
+			Objs:               o,
+			Render:             render,
+			Namespace:          namespace,
+			KedaNamespace:      kedaNamespace,
+			DryRun:             dryRun,
+			MissingPermissions: missingPermissions,
 		},
 		K8s: reconciler.K8s{
 			Client:        c,
 			EventRecorder: r,
+			Discovery:     d,
 		},
 	}
 }
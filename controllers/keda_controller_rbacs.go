@@ -1,20 +1,7 @@
 package controllers
 
-//+kubebuilder:rbac:groups="*",resources="*",verbs=get
-//+kubebuilder:rbac:groups=external.metrics.k8s.io,resources="*",verbs="*"
-//+kubebuilder:rbac:groups="",resources=configmaps;configmaps/status;events;services,verbs="*"
-//+kubebuilder:rbac:groups="",resources=external;pods;secrets;serviceaccounts,verbs=list;watch;create;delete;update;patch
-//+kubebuilder:rbac:groups="",resources=namespaces,verbs=create;delete
-//+kubebuilder:rbac:groups=apiregistration.k8s.io,resources=apiservices,verbs=create;delete;update;patch;watch;list
-//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterrolebindings;clusterroles;rolebindings,verbs=create;delete;update;patch;watch;list
-//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=create;delete;update;patch;watch;list
-//+kubebuilder:rbac:groups="*",resources="*/scale",verbs="*"
-//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=list;watch;create;delete;update;patch
-//+kubebuilder:rbac:groups=apps,resources=statefulsets;replicasets,verbs=list;watch
-//+kubebuilder:rbac:groups=batch,resources=jobs,verbs="*"
-//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs="*"
-//+kubebuilder:rbac:groups="keda.sh",resources=clustertriggerauthentications;clustertriggerauthentications/status;scaledjobs;scaledjobs/finalizers;scaledjobs/status;scaledobjects;scaledobjects/finalizers;scaledobjects/status;triggerauthentications;triggerauthentications/status,verbs="*"
-//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs="*"
+// permissions needed to manage keda objects are derived from the keda manifest
+//go:generate go run ../hack/rbac-gen --manifest-path ../keda-manager.yaml --output keda_manifest_rbacs.go
 
 //+kubebuilder:rbac:groups=operator.kyma-project.io,resources=kedas,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=operator.kyma-project.io,resources=kedas/status,verbs=get;update;patch
@@ -18,6 +18,7 @@ package controllers
 
 import (
 	"context"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/tools/record"
 	"os"
 	"path/filepath"
@@ -38,6 +39,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	operatorv1alpha1 "github.com/kyma-project/keda-manager/api/v1alpha1"
+	operatorv1beta1 "github.com/kyma-project/keda-manager/api/v1beta1"
 	"github.com/kyma-project/keda-manager/pkg/reconciler"
 	"github.com/kyma-project/keda-manager/pkg/yaml"
 	//+kubebuilder:scaffold:imports
@@ -59,31 +61,42 @@ var _ = BeforeSuite(func() {
 	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
 
 	By("bootstrapping test environment")
+	// both versions are registered before the environment starts, so that
+	// the conversion webhook of the Keda CRD is configured
+	err := operatorv1alpha1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+	err = operatorv1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	testEnv = &envtest.Environment{
 		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
 		ErrorIfCRDPathMissing: true,
+		WebhookInstallOptions: envtest.WebhookInstallOptions{},
 	}
 
-	var err error
 	// config is defined in this file globally.
 	config, err = testEnv.Start()
 	Expect(err).NotTo(HaveOccurred())
 	Expect(config).NotTo(BeNil())
 
-	err = operatorv1alpha1.AddToScheme(scheme.Scheme)
-	Expect(err).NotTo(HaveOccurred())
-
 	//+kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(config, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	webhookOptions := testEnv.WebhookInstallOptions
 	k8sManager, err := ctrl.NewManager(config, ctrl.Options{
-		Scheme: scheme.Scheme,
+		Scheme:  scheme.Scheme,
+		Host:    webhookOptions.LocalServingHost,
+		Port:    webhookOptions.LocalServingPort,
+		CertDir: webhookOptions.LocalServingCertDir,
 	})
 	Expect(err).ToNot(HaveOccurred())
 
+	err = (&operatorv1beta1.Keda{}).SetupWebhookWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
 	config := uzap.NewDevelopmentConfig()
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.Encoding = "json"
@@ -103,6 +116,7 @@ var _ = BeforeSuite(func() {
 		K8s: reconciler.K8s{
 			Client:        k8sManager.GetClient(),
 			EventRecorder: record.NewFakeRecorder(100),
+			Discovery:     discovery.NewDiscoveryClientForConfigOrDie(k8sManager.GetConfig()),
 		},
 		Cfg: reconciler.Cfg{
 			Finalizer: "keda-manager.kyma-project.io/deletion-hook",
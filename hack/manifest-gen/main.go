@@ -0,0 +1,44 @@
+// manifest-gen renders the keda manifest from the keda chart with its
+// default values; the chart is the source of the manifest
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kyma-project/keda-manager/pkg/helm"
+)
+
+func main() {
+	var chartPath string
+	var outputPath string
+	var namespace string
+	flag.StringVar(&chartPath, "chart-path", "charts/keda", "The path to the keda chart.")
+	flag.StringVar(&outputPath, "output", "keda-manager.yaml", "The path to the generated manifest.")
+	flag.StringVar(&namespace, "target-namespace", "kyma-system", "The namespace keda components are installed in.")
+	flag.Parse()
+
+	if err := generate(chartPath, outputPath, namespace); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(chartPath, outputPath, namespace string) error {
+	chart, err := helm.Load(chartPath)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := helm.Manifest(chart, namespace, nil)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "# Code generated by hack/manifest-gen from the keda chart. DO NOT EDIT.")
+	out.Write(manifest)
+	return os.WriteFile(outputPath, out.Bytes(), 0644)
+}
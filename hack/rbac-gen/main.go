@@ -0,0 +1,62 @@
+// rbac-gen generates kubebuilder markers granting keda-manager permissions
+// needed to manage objects of the keda manifest
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kyma-project/keda-manager/pkg/rbac"
+	"github.com/kyma-project/keda-manager/pkg/reconciler"
+	"github.com/kyma-project/keda-manager/pkg/yaml"
+)
+
+func main() {
+	var manifestPath string
+	var outputPath string
+	var namespace string
+	flag.StringVar(&manifestPath, "manifest-path", "keda-manager.yaml", "The path to the manifest with keda objects.")
+	flag.StringVar(&outputPath, "output", "controllers/keda_manifest_rbacs.go", "The path to the generated file.")
+	flag.StringVar(&namespace, "target-namespace", "kyma-system", "The namespace keda components are installed in.")
+	flag.Parse()
+
+	if err := generate(manifestPath, outputPath, namespace); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(manifestPath, outputPath, namespace string) error {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	objs, err := yaml.LoadData(file)
+	if err != nil {
+		return err
+	}
+
+	managed, err := reconciler.ManagedObjs(objs, namespace)
+	if err != nil {
+		return err
+	}
+
+	rules, err := rbac.Rules(managed)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "// Code generated by hack/rbac-gen from the keda manifest. DO NOT EDIT.")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "package controllers")
+	fmt.Fprintln(&out)
+	for _, marker := range rbac.Markers(rules) {
+		fmt.Fprintln(&out, marker)
+	}
+	return os.WriteFile(outputPath, out.Bytes(), 0644)
+}
@@ -17,41 +17,68 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"embed"
 	"flag"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
+	"time"
 
+	"github.com/go-logr/zapr"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
 	zapk8s "sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"github.com/kyma-project/keda-manager/pkg/helm"
 	"github.com/kyma-project/keda-manager/pkg/keda"
+	"helm.sh/helm/v3/pkg/chart"
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
 	operatorv1alpha1 "github.com/kyma-project/keda-manager/api/v1alpha1"
+	operatorv1beta1 "github.com/kyma-project/keda-manager/api/v1beta1"
 	"github.com/kyma-project/keda-manager/controllers"
+	"github.com/kyma-project/keda-manager/pkg/rbac"
+	"github.com/kyma-project/keda-manager/pkg/reconciler"
+	"github.com/kyma-project/keda-manager/pkg/tracing"
 	"github.com/kyma-project/keda-manager/pkg/yaml"
 	//+kubebuilder:scaffold:imports
 )
 
+const (
+	// the cluster is discovered again by preflight checks after the ttl
+	discoveryTTL = 10 * time.Minute
+)
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
+
+	// the chart is the source of keda objects; the keda-manager.yaml
+	// manifest is generated from it with make keda-manifest
+	//go:embed all:charts/keda
+	chartFS embed.FS
 )
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 	utilruntime.Must(operatorv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(operatorv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -59,17 +86,74 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var targetNamespace string
+	var chartPath string
+	var chartValuesPath string
+	var manifestPath string
+	var dryRun bool
+	var otlpEndpoint string
+	var kedaNamespace string
+	var enableWebhooks bool
+	var maxConcurrentReconciles int
+	var logLevelAddr string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&logLevelAddr, "log-level-bind-address", "127.0.0.1:8082",
+		"The address the log level endpoint binds to. It is not authenticated, so it should stay on localhost. Set to 0 to disable it.")
+	flag.StringVar(&targetNamespace, "target-namespace", "kyma-system",
+		"The namespace keda components are installed in, unless the Keda instance specifies one.")
+	flag.StringVar(&kedaNamespace, "keda-namespace", defaultKedaNamespace(),
+		"The namespace of Keda instances; instances in other namespaces are reported as misplaced. "+
+			"Defaults to the POD_NAMESPACE environment variable, or kyma-system if it is not set.")
+	flag.StringVar(&manifestPath, "manifest-path", "",
+		"The path to the manifest with keda objects; objects are rendered from the keda chart if not set.")
+	flag.StringVar(&chartPath, "chart-path", "",
+		"The path to the keda chart the objects are rendered from; the chart embedded in the binary is used if not set.")
+	flag.StringVar(&chartValuesPath, "chart-values", "",
+		"The path to the values file used to render the keda chart; values of Keda instances take precedence over it.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Report changes keda-manager would apply in the dry run configmap of every Keda instance, without applying them.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"The OTLP gRPC endpoint reconciliation traces are exported to; "+
+			"the OTEL_EXPORTER_OTLP_ENDPOINT environment variable is used if not set, tracing is disabled if neither is set.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", true,
+		"Serve the conversion webhook of the Keda CRD, which serves the v1beta1 API; "+
+			"the serving certificate is read from the webhook server's cert dir.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The maximum number of Keda instances reconciled at a time.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
-	//FIXME use parameter
 	opts := zapk8s.Options{}
 	opts.BindFlags(flag.CommandLine)
+	// environment variables are defaults of logging flags
+	if err := setFlagsFromEnv(flag.CommandLine, logFlagEnvs); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 	flag.Parse()
 
-	ctrl.SetLogger(zapk8s.New(zapk8s.UseFlagOptions(&opts)))
+	logLevel := atomicLogLevel(&opts)
+	logger := zapk8s.NewRaw(zapk8s.UseFlagOptions(&opts))
+	ctrl.SetLogger(zapr.NewLogger(logger))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), otlpEndpoint)
+	if err != nil {
+		setupLog.Error(err, "unable to setup tracing")
+		os.Exit(1)
+	}
+
+	data, render, err := loadObjs(manifestPath, chartPath, chartValuesPath, targetNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to load k8s data")
+		os.Exit(1)
+	}
+
+	if err := reconciler.ValidateObjs(data); err != nil {
+		setupLog.Error(err, "unable to use k8s data")
+		os.Exit(1)
+	}
+
 	restConfig := ctrl.GetConfigOrDie()
 
 	kedaInstalled, err := keda.IsInstalled(restConfig, setupLog)
@@ -82,6 +166,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	missingPermissions, err := checkPermissions(restConfig, data, targetNamespace)
+	if err != nil {
+		setupLog.Error(err, "unable to check permissions")
+		os.Exit(1)
+	}
+	if len(missingPermissions) > 0 {
+		setupLog.Info("keda-manager is missing permissions, keda components will not be reconciled",
+			"missingPermissions", missingPermissions)
+	}
+
 	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -89,6 +183,9 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "4123c01c.operator.kyma-project.io",
+		// Secrets and ConfigMaps are watched by their metadata only, so
+		// they are read bypassing the cache
+		ClientDisableCacheFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -106,40 +203,40 @@ func main() {
 		os.Exit(1)
 	}
 
-	file, err := os.Open("keda-manager.yaml")
-	if err != nil {
-		setupLog.Error(err, "unable to open k8s data")
+	// the log level can be read and changed at runtime with GET and PUT requests;
+	// it is served apart from the metrics, as the metrics address may be public
+	if logLevelAddr != "0" {
+		if err := mgr.Add(&logLevelServer{addr: logLevelAddr, level: logLevel}); err != nil {
+			setupLog.Error(err, "unable to set up log level endpoint")
+			os.Exit(1)
+		}
 	}
 
-	data, err := yaml.LoadData(file)
-	if err != nil {
-		setupLog.Error(err, "unable to load k8s data")
-		os.Exit(1)
-	}
-
-	config := zap.NewDevelopmentConfig()
-	config.EncoderConfig.TimeKey = "timestamp"
-	config.Encoding = "json"
-	config.EncoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("Jan 02 15:04:05.000000000")
-
-	kedaLogger, err := config.Build()
-	if err != nil {
-		setupLog.Error(err, "unable to setup logger")
-		os.Exit(1)
-	}
-
-	setupLog.Info(fmt.Sprintf("log level set to: %s", kedaLogger.Level()))
+	setupLog.Info(fmt.Sprintf("log level set to: %s", logLevel.Level()))
 
 	kedaReconciler := controllers.NewKedaReconciler(
 		mgr.GetClient(),
 		mgr.GetEventRecorderFor("keda-manager"),
-		kedaLogger.Sugar(),
+		reconciler.NewCachedDiscovery(discovery.NewDiscoveryClientForConfigOrDie(restConfig), discoveryTTL),
+		logger.Sugar(),
 		data,
+		render,
+		targetNamespace,
+		kedaNamespace,
+		dryRun,
+		missingPermissions,
+		maxConcurrentReconciles,
 	)
 	if err = kedaReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Keda")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = (&operatorv1beta1.Keda{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Keda")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -156,4 +253,166 @@ func main() {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
+}
+
+// defaultKedaNamespace is the namespace keda-manager runs in
+func defaultKedaNamespace() string {
+	if namespace, found := os.LookupEnv("POD_NAMESPACE"); found && namespace != "" {
+		return namespace
+	}
+	return "kyma-system"
+}
+
+var (
+	// environment variables used if the logging flags are not given
+	logFlagEnvs = map[string]string{
+		"zap-log-level":     "LOG_LEVEL",
+		"zap-encoder":       "LOG_FORMAT",
+		"zap-time-encoding": "LOG_TIME_ENCODING",
+	}
+)
+
+func setFlagsFromEnv(fs *flag.FlagSet, envs map[string]string) error {
+	for name, env := range envs {
+		value, found := os.LookupEnv(env)
+		if !found {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s environment variable: %w", env, err)
+		}
+	}
+	return nil
+}
+
+// atomicLogLevel makes the level of the logger built from the options changeable at runtime
+func atomicLogLevel(opts *zapk8s.Options) zap.AtomicLevel {
+	if level, ok := opts.Level.(zap.AtomicLevel); ok {
+		return level
+	}
+
+	level := zap.NewAtomicLevelAt(zap.InfoLevel)
+	if opts.Development {
+		level.SetLevel(zap.DebugLevel)
+	}
+	opts.Level = level
+	return level
+}
+
+// logLevelServer serves the log level on its own address until the manager stops
+type logLevelServer struct {
+	addr  string
+	level zap.AtomicLevel
+}
+
+func (s *logLevelServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/log-level", s.level)
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// NeedLeaderElection lets every replica serve its own log level
+func (s *logLevelServer) NeedLeaderElection() bool {
+	return false
+}
+
+// checkPermissions returns permissions keda-manager needs to manage given
+// objects, but which are not granted to it
+func checkPermissions(config *rest.Config, objs []unstructured.Unstructured, namespace string) ([]string, error) {
+	c, err := client.New(config, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	managed, err := reconciler.ManagedObjs(objs, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := rbac.Rules(managed)
+	if err != nil {
+		return nil, err
+	}
+
+	return rbac.MissingPermissions(context.Background(), c, rules)
+}
+
+// loadObjs loads keda objects from the manifest given by path, or renders them
+// from the chart given by path; the embedded chart is used if neither of them
+// is given. Objects rendered from the chart are rendered again with values of
+// Keda instances with the returned function
+func loadObjs(manifestPath, chartPath, chartValuesPath, namespace string) ([]unstructured.Unstructured, reconciler.RenderFunc, error) {
+	if manifestPath != "" && chartPath == "" {
+		objs, err := loadManifest(manifestPath)
+		return objs, nil, err
+	}
+
+	renderer, err := newRenderer(chartPath, chartValuesPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	objs, err := renderer.Render(operatorv1alpha1.KedaSpec{}, namespace)
+	return objs, renderer.Render, err
+}
+
+func loadManifest(manifestPath string) ([]unstructured.Unstructured, error) {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return yaml.LoadData(file)
+}
+
+func newRenderer(chartPath, valuesPath string) (*helm.Renderer, error) {
+	chart, err := loadChart(chartPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	if valuesPath != "" {
+		values, err = helm.LoadValues(valuesPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return helm.NewRenderer(chart, values), nil
+}
+
+func loadChart(chartPath string) (*chart.Chart, error) {
+	if chartPath != "" {
+		return helm.Load(chartPath)
+	}
+
+	embedded, err := fs.Sub(chartFS, "charts/keda")
+	if err != nil {
+		return nil, err
+	}
+	return helm.LoadFS(embedded)
 }
@@ -0,0 +1,212 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	CAValidity      = 5 * 365 * 24 * time.Hour
+	ServingValidity = 365 * 24 * time.Hour
+
+	caCommonName = "keda-manager-ca"
+)
+
+var (
+	ErrInvalidPEM = errors.New("invalid PEM data")
+)
+
+// Bundle is the CA and the serving certificate signed by it, PEM encoded
+type Bundle struct {
+	CACert []byte
+	CAKey  []byte
+	Cert   []byte
+	Key    []byte
+}
+
+func newKey() (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func ParseCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, ErrInvalidPEM
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parseKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, ErrInvalidPEM
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// generateCA returns a self-signed CA certificate and its key
+func generateCA(now time.Time) ([]byte, []byte, error) {
+	key, keyPEM, err := newKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: caCommonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(CAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(der), keyPEM, nil
+}
+
+// generateServing returns a serving certificate for given DNS names signed by the CA
+func generateServing(caCertPEM, caKeyPEM []byte, dnsNames []string, now time.Time) ([]byte, []byte, error) {
+	caCert, err := ParseCert(caCertPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CA certificate: %w", err)
+	}
+	caKey, err := parseKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CA key: %w", err)
+	}
+
+	key, keyPEM, err := newKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(ServingValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(der), keyPEM, nil
+}
+
+// RenewAt returns the time the certificate should be renewed at, which is
+// after two thirds of its validity
+func RenewAt(cert *x509.Certificate) time.Time {
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotAfter.Add(-validity / 3)
+}
+
+func caValid(b Bundle, now time.Time) bool {
+	cert, err := ParseCert(b.CACert)
+	if err != nil || !cert.IsCA || now.After(RenewAt(cert)) {
+		return false
+	}
+	_, err = parseKey(b.CAKey)
+	return err == nil
+}
+
+func servingValid(b Bundle, dnsNames []string, now time.Time) bool {
+	cert, err := ParseCert(b.Cert)
+	if err != nil || now.After(RenewAt(cert)) {
+		return false
+	}
+	if _, err := parseKey(b.Key); err != nil {
+		return false
+	}
+	for _, name := range dnsNames {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(b.CACert) {
+		return false
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:     dnsNames[0],
+		Roots:       roots,
+		CurrentTime: now,
+	})
+	return err == nil
+}
+
+// Ensure returns the bundle with certificates valid for given DNS names;
+// certificates of the given bundle are reused unless they are due to renew
+func Ensure(b Bundle, dnsNames []string, now time.Time) (Bundle, error) {
+	if len(dnsNames) == 0 {
+		return Bundle{}, errors.New("no DNS names given")
+	}
+
+	result := b
+	if !caValid(result, now) {
+		caCert, caKey, err := generateCA(now)
+		if err != nil {
+			return Bundle{}, err
+		}
+		result = Bundle{CACert: caCert, CAKey: caKey}
+	}
+
+	if servingValid(result, dnsNames, now) {
+		return result, nil
+	}
+
+	cert, key, err := generateServing(result.CACert, result.CAKey, dnsNames, now)
+	if err != nil {
+		return Bundle{}, err
+	}
+	result.Cert = cert
+	result.Key = key
+	return result, nil
+}
+
+// Equal checks if bundles contain the same certificates
+func (b Bundle) Equal(other Bundle) bool {
+	return bytes.Equal(b.CACert, other.CACert) &&
+		bytes.Equal(b.CAKey, other.CAKey) &&
+		bytes.Equal(b.Cert, other.Cert) &&
+		bytes.Equal(b.Key, other.Key)
+}
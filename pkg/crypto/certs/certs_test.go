@@ -0,0 +1,92 @@
+package certs
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testDNSNames = []string{
+		"keda-manager-metrics-apiserver.kyma-system.svc",
+		"keda-manager-metrics-apiserver.kyma-system.svc.cluster.local",
+	}
+)
+
+func TestEnsure(t *testing.T) {
+	now := time.Now()
+
+	t.Run("generate", func(t *testing.T) {
+		b, err := Ensure(Bundle{}, testDNSNames, now)
+		require.NoError(t, err)
+
+		ca, err := ParseCert(b.CACert)
+		require.NoError(t, err)
+		require.True(t, ca.IsCA)
+
+		cert, err := ParseCert(b.Cert)
+		require.NoError(t, err)
+		require.Equal(t, testDNSNames, cert.DNSNames)
+
+		roots := x509.NewCertPool()
+		require.True(t, roots.AppendCertsFromPEM(b.CACert))
+		_, err = cert.Verify(x509.VerifyOptions{DNSName: testDNSNames[1], Roots: roots})
+		require.NoError(t, err)
+	})
+
+	t.Run("reuse valid certificates", func(t *testing.T) {
+		b, err := Ensure(Bundle{}, testDNSNames, now)
+		require.NoError(t, err)
+
+		result, err := Ensure(b, testDNSNames, now.Add(24*time.Hour))
+		require.NoError(t, err)
+		require.True(t, b.Equal(result))
+	})
+
+	t.Run("renew serving certificate", func(t *testing.T) {
+		b, err := Ensure(Bundle{}, testDNSNames, now)
+		require.NoError(t, err)
+
+		later := now.Add(ServingValidity * 3 / 4)
+		result, err := Ensure(b, testDNSNames, later)
+		require.NoError(t, err)
+		require.Equal(t, b.CACert, result.CACert)
+		require.NotEqual(t, b.Cert, result.Cert)
+	})
+
+	t.Run("renew for new dns names", func(t *testing.T) {
+		b, err := Ensure(Bundle{}, testDNSNames[:1], now)
+		require.NoError(t, err)
+
+		result, err := Ensure(b, testDNSNames, now)
+		require.NoError(t, err)
+		require.Equal(t, b.CACert, result.CACert)
+		require.NotEqual(t, b.Cert, result.Cert)
+	})
+
+	t.Run("renew CA", func(t *testing.T) {
+		b, err := Ensure(Bundle{}, testDNSNames, now)
+		require.NoError(t, err)
+
+		later := now.Add(CAValidity * 3 / 4)
+		result, err := Ensure(b, testDNSNames, later)
+		require.NoError(t, err)
+		require.NotEqual(t, b.CACert, result.CACert)
+		require.NotEqual(t, b.Cert, result.Cert)
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		result, err := Ensure(Bundle{CACert: []byte("invalid"), Cert: []byte("invalid")}, testDNSNames, now)
+		require.NoError(t, err)
+		_, err = ParseCert(result.CACert)
+		require.NoError(t, err)
+	})
+}
+
+func TestRenewAt(t *testing.T) {
+	now := time.Now()
+	cert := &x509.Certificate{NotBefore: now, NotAfter: now.Add(3 * time.Hour)}
+	require.Equal(t, now.Add(2*time.Hour), RenewAt(cert))
+}
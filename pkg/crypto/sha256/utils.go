@@ -3,8 +3,10 @@ package sha256
 import (
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
@@ -36,3 +38,42 @@ func (w Calculator) CalculateSum(obj unstructured.Unstructured) (string, error)
 
 	return base64.URLEncoding.EncodeToString(sha.Sum(nil)), nil
 }
+
+// CalculateContentSum returns the checksum of the whole object content;
+// maps are encoded with sorted keys, so equal objects have equal checksums
+func (w Calculator) CalculateContentSum(obj unstructured.Unstructured) (string, error) {
+	data, err := json.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+
+	sha := w()
+	if _, err := sha.Write(data); err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(sha.Sum(nil)), nil
+}
+
+// CalculateDataSum returns the checksum of given data; entries are hashed
+// in order of their keys, so equal data has equal checksums
+func (w Calculator) CalculateDataSum(data map[string][]byte) (string, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sha := w()
+	for _, key := range keys {
+		// lengths keep boundaries of keys and values unambiguous
+		if _, err := fmt.Fprintf(sha, "%d:%s:%d:", len(key), key, len(data[key])); err != nil {
+			return "", err
+		}
+		if _, err := sha.Write(data[key]); err != nil {
+			return "", err
+		}
+	}
+
+	return base64.URLEncoding.EncodeToString(sha.Sum(nil)), nil
+}
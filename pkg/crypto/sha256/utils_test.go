@@ -76,3 +76,66 @@ func Test_calculateSHA256(t *testing.T) {
 		})
 	}
 }
+
+func Test_CalculateDataSum(t *testing.T) {
+	sum := func(data map[string][]byte) string {
+		got, err := sha256.DefaultCalculator.CalculateDataSum(data)
+		if err != nil {
+			t.Fatalf("CalculateDataSum() error = %v", err)
+		}
+		return got
+	}
+
+	data := map[string][]byte{"Secret/a/key": []byte("value"), "ConfigMap/b/key": []byte("value")}
+	if sum(data) != sum(map[string][]byte{"ConfigMap/b/key": []byte("value"), "Secret/a/key": []byte("value")}) {
+		t.Errorf("CalculateDataSum() depends on the order of entries")
+	}
+	if sum(data) == sum(map[string][]byte{"Secret/a/key": []byte("changed"), "ConfigMap/b/key": []byte("value")}) {
+		t.Errorf("CalculateDataSum() ignores changed values")
+	}
+	if sum(map[string][]byte{"a": []byte("bc")}) == sum(map[string][]byte{"ab": []byte("c")}) {
+		t.Errorf("CalculateDataSum() ignores boundaries of keys and values")
+	}
+
+	ws := sha256mock.NewWriterSumer(t)
+	ws.On("Write", mock.AnythingOfType("[]uint8")).Return(0, errTest).Once()
+	calculator := sha256.Calculator(func() sha256.WriterSumer { return ws })
+	if _, err := calculator.CalculateDataSum(data); err == nil {
+		t.Errorf("CalculateDataSum() expected write error")
+	}
+}
+
+func Test_CalculateContentSum(t *testing.T) {
+	obj := func(image string) unstructured.Unstructured {
+		var u unstructured.Unstructured
+		u.SetAPIVersion("apps/v1")
+		u.SetKind("Deployment")
+		u.SetName("keda-manager")
+		if err := unstructured.SetNestedField(u.Object, image, "spec", "image"); err != nil {
+			t.Fatal(err)
+		}
+		return u
+	}
+	sum := func(u unstructured.Unstructured) string {
+		got, err := sha256.DefaultCalculator.CalculateContentSum(u)
+		if err != nil {
+			t.Fatalf("CalculateContentSum() error = %v", err)
+		}
+		return got
+	}
+
+	if sum(obj("keda:2.8.0")) != sum(obj("keda:2.8.0")) {
+		t.Errorf("CalculateContentSum() differs for equal objects")
+	}
+	if sum(obj("keda:2.8.0")) == sum(obj("keda:2.9.0")) {
+		t.Errorf("CalculateContentSum() ignores changed content")
+	}
+	// unlike CalculateSum, the content is hashed
+	kindSum, err := sha256.DefaultCalculator.CalculateSum(obj("keda:2.8.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kindSum == sum(obj("keda:2.8.0")) {
+		t.Errorf("CalculateContentSum() hashes the kind only")
+	}
+}
@@ -0,0 +1,348 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/yaml"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const releaseName = "keda-manager"
+
+var toUnstructured = runtime.DefaultUnstructuredConverter.ToUnstructured
+
+// Load loads the chart from given directory or archive
+func Load(chartPath string) (*chart.Chart, error) {
+	return loader.Load(chartPath)
+}
+
+// LoadValues loads the values from given file
+func LoadValues(valuesPath string) (map[string]interface{}, error) {
+	return chartutil.ReadValuesFile(valuesPath)
+}
+
+// Render renders the chart templates with given values and returns the rendered objects;
+// values are merged with the chart default values
+func Render(c *chart.Chart, namespace string, values map[string]interface{}) ([]unstructured.Unstructured, error) {
+	manifest, err := Manifest(c, namespace, values)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.LoadData(bytes.NewReader(manifest))
+}
+
+// Manifest renders the chart templates with given values into a single
+// manifest, in the same format as helm template does
+func Manifest(c *chart.Chart, namespace string, values map[string]interface{}) ([]byte, error) {
+	options := chartutil.ReleaseOptions{
+		Name:      releaseName,
+		Namespace: namespace,
+		Revision:  1,
+		IsInstall: true,
+	}
+
+	renderValues, err := chartutil.ToRenderValues(c, values, options, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := engine.Render(c, renderValues)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if !isManifest(name) || strings.TrimSpace(files[name]) == "" {
+			continue
+		}
+		names = append(names, name)
+	}
+	// render objects in the same order as the template files are named
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		fmt.Fprintf(&buf, "# Source: %s\n", name)
+		buf.WriteString(strings.TrimSpace(files[name]))
+		buf.WriteString("\n")
+	}
+	return buf.Bytes(), nil
+}
+
+func isManifest(name string) bool {
+	base := path.Base(name)
+	if strings.HasPrefix(base, "_") {
+		return false
+	}
+	ext := path.Ext(base)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ValuesFromSpec builds chart values out of the Keda spec; only properties
+// set in the spec are returned and, as usual for helm values, they are
+// merged with the chart defaults
+func ValuesFromSpec(spec v1alpha1.KedaSpec) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	if spec.Logging != nil && spec.Logging.Operator != nil {
+		operator := map[string]interface{}{}
+		if spec.Logging.Operator.Level != nil {
+			operator["level"] = string(*spec.Logging.Operator.Level)
+		}
+		if spec.Logging.Operator.Format != nil {
+			operator["format"] = string(*spec.Logging.Operator.Format)
+		}
+		if spec.Logging.Operator.TimeEncoding != nil {
+			operator["timeEncoding"] = string(*spec.Logging.Operator.TimeEncoding)
+		}
+		setValue(values, operator, "logging", "operator")
+	}
+
+	if spec.Logging != nil && spec.Logging.MetricsServer != nil && spec.Logging.MetricsServer.Level != nil {
+		metricServer := map[string]interface{}{
+			"level": string(*spec.Logging.MetricsServer.Level),
+		}
+		setValue(values, metricServer, "logging", "metricServer")
+	}
+
+	if spec.Resources != nil && spec.Resources.Operator != nil {
+		operator, err := toUnstructured(spec.Resources.Operator)
+		if err != nil {
+			return nil, err
+		}
+		setValue(values, operator, "resources", "operator")
+	}
+
+	if spec.Resources != nil && spec.Resources.MetricsServer != nil {
+		metricServer, err := toUnstructured(spec.Resources.MetricsServer)
+		if err != nil {
+			return nil, err
+		}
+		setValue(values, metricServer, "resources", "metricServer")
+	}
+
+	if len(spec.Env) > 0 {
+		env := make([]interface{}, 0, len(spec.Env))
+		for i := range spec.Env {
+			e, err := toUnstructured(&spec.Env[i])
+			if err != nil {
+				return nil, err
+			}
+			env = append(env, e)
+		}
+		values["env"] = env
+	}
+
+	if spec.Monitoring != nil && spec.Monitoring.Enabled {
+		setMonitoringValues(values, *spec.Monitoring)
+	}
+
+	if spec.PodIdentity != nil {
+		setPodIdentityValues(values, *spec.PodIdentity)
+	}
+
+	if spec.Security != nil {
+		if err := setSecurityValues(values, *spec.Security); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// setMonitoringValues exposes metrics of keda components with pod annotations
+// or, in the monitors mode, with monitors; an unset mode is resolved by the
+// reconciler, as it depends on the CRDs installed on the cluster
+func setMonitoringValues(values map[string]interface{}, monitoring v1alpha1.Monitoring) {
+	monitors := monitoring.Mode != nil && *monitoring.Mode == v1alpha1.MonitoringModeMonitors
+	for component, monitor := range map[string]string{
+		"operator":     "podMonitor",
+		"metricServer": "serviceMonitor",
+	} {
+		setValue(values, true, "prometheus", component, "enabled")
+		if !monitors {
+			continue
+		}
+		setValue(values, true, "prometheus", component, monitor, "enabled")
+		if monitoring.Interval != nil {
+			setValue(values, *monitoring.Interval, "prometheus", component, monitor, "interval")
+		}
+	}
+}
+
+// setPodIdentityValues enables the pod identity of the selected provider;
+// settings of other providers are rejected by the reconciler
+func setPodIdentityValues(values map[string]interface{}, cfg v1alpha1.PodIdentityCfg) {
+	switch {
+	case cfg.Provider == v1alpha1.PodIdentityProviderAzure && cfg.Azure != nil:
+		setValue(values, cfg.Azure.Identity, "podIdentity", "activeDirectory", "identity")
+	case cfg.Provider == v1alpha1.PodIdentityProviderAzureWorkload && cfg.AzureWorkload != nil:
+		azureWorkload := map[string]interface{}{
+			"enabled":  true,
+			"clientId": cfg.AzureWorkload.ClientID,
+		}
+		if cfg.AzureWorkload.TenantID != nil {
+			azureWorkload["tenantId"] = *cfg.AzureWorkload.TenantID
+		}
+		if cfg.AzureWorkload.TokenExpiration != nil {
+			azureWorkload["tokenExpiration"] = *cfg.AzureWorkload.TokenExpiration
+		}
+		setValue(values, azureWorkload, "podIdentity", "azureWorkload")
+	case cfg.Provider == v1alpha1.PodIdentityProviderAWSEKS && cfg.AWSEKS != nil:
+		irsa := map[string]interface{}{
+			"enabled": true,
+			"roleArn": cfg.AWSEKS.RoleArn,
+		}
+		setValue(values, irsa, "podIdentity", "aws", "irsa")
+	case cfg.Provider == v1alpha1.PodIdentityProviderGCP && cfg.GCP != nil:
+		gcp := map[string]interface{}{
+			"enabled":              true,
+			"gcpIAMServiceAccount": cfg.GCP.ServiceAccount,
+		}
+		setValue(values, gcp, "podIdentity", "gcp")
+	}
+}
+
+// setSecurityValues sets the security context of keda pods and containers;
+// as the pod seccomp profile must not be overridden by containers, nil
+// removes the chart default of the container seccomp profile
+func setSecurityValues(values map[string]interface{}, cfg v1alpha1.SecurityCfg) error {
+	podSecurityContext, err := toUnstructured(&corev1.PodSecurityContext{
+		RunAsUser:      cfg.RunAsUser,
+		RunAsGroup:     cfg.RunAsGroup,
+		FSGroup:        cfg.FSGroup,
+		SeccompProfile: cfg.SeccompProfile,
+	})
+	if err != nil {
+		return err
+	}
+	for key, value := range podSecurityContext {
+		setValue(values, value, "podSecurityContext", key)
+	}
+
+	for _, container := range []string{"operator", "metricServer"} {
+		if cfg.ReadOnlyRootFilesystem != nil {
+			setValue(values, *cfg.ReadOnlyRootFilesystem, "securityContext", container, "readOnlyRootFilesystem")
+		}
+		if cfg.SeccompProfile != nil {
+			setValue(values, nil, "securityContext", container, "seccompProfile")
+		}
+	}
+
+	if cfg.PriorityClassName != nil {
+		values["priorityClassName"] = *cfg.PriorityClassName
+	}
+	if cfg.AutomountServiceAccountToken != nil {
+		setValue(values, *cfg.AutomountServiceAccountToken, "serviceAccount", "automountServiceAccountToken")
+	}
+	return nil
+}
+
+// MergeValues merges values giving precedence to the values passed first;
+// nil values are kept, so they still remove the chart defaults
+func MergeValues(values ...map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for i := len(values) - 1; i >= 0; i-- {
+		result = mergeMaps(result, values[i])
+	}
+	return result
+}
+
+// mergeMaps returns a copy of dst with tables of src merged into it
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(dst))
+	for key, value := range dst {
+		result[key] = value
+	}
+	for key, value := range src {
+		if table, ok := value.(map[string]interface{}); ok {
+			if dstTable, ok := result[key].(map[string]interface{}); ok {
+				result[key] = mergeMaps(dstTable, table)
+				continue
+			}
+		}
+		result[key] = value
+	}
+	return result
+}
+
+func setValue(values map[string]interface{}, value interface{}, keys ...string) {
+	current := values
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
+
+// Renderer renders the chart with values of Keda instances
+type Renderer struct {
+	// rendering merges values into the shared chart values
+	mu     sync.Mutex
+	chart  *chart.Chart
+	values map[string]interface{}
+}
+
+// NewRenderer returns the renderer of given chart; given values take
+// precedence over the chart default values, but not over values of
+// Keda instances
+func NewRenderer(c *chart.Chart, values map[string]interface{}) *Renderer {
+	return &Renderer{
+		chart:  c,
+		values: values,
+	}
+}
+
+// Render renders the chart into given namespace with values built out of given Keda spec
+func (r *Renderer) Render(spec v1alpha1.KedaSpec, namespace string) ([]unstructured.Unstructured, error) {
+	values, err := ValuesFromSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Render(r.chart, namespace, MergeValues(values, r.values))
+}
+
+// LoadFS loads the chart from the root of given file system
+func LoadFS(fsys fs.FS) (*chart.Chart, error) {
+	var files []*loader.BufferedFile
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, &loader.BufferedFile{Name: name, Data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadFiles(files)
+}
@@ -0,0 +1,273 @@
+package helm
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/yaml"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testChartPath = "../../charts/keda"
+
+func findObj(objs []unstructured.Unstructured, kind, name string) *unstructured.Unstructured {
+	for i := range objs {
+		if objs[i].GetKind() == kind && objs[i].GetName() == name {
+			return &objs[i]
+		}
+	}
+	return nil
+}
+
+func Test_Render(t *testing.T) {
+	c, err := Load(testChartPath)
+	require.NoError(t, err)
+
+	t.Run("default values", func(t *testing.T) {
+		objs, err := Render(c, "kyma-system", nil)
+		require.NoError(t, err)
+		require.Len(t, objs, 15)
+
+		// CRDs are applied first
+		require.Equal(t, "CustomResourceDefinition", objs[0].GetKind())
+
+		deployment := findObj(objs, "Deployment", "keda-manager")
+		require.NotNil(t, deployment)
+		require.Equal(t, "kyma-system", deployment.GetNamespace())
+		require.Equal(t, "keda-manager", deployment.GetLabels()["app.kubernetes.io/part-of"])
+
+		containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+		container := containers[0].(map[string]interface{})
+		require.Equal(t, "ghcr.io/kedacore/keda:2.8.0", container["image"])
+		require.Contains(t, container["args"], "--zap-log-level=info")
+
+		require.NotNil(t, findObj(objs, "Deployment", "keda-manager-metrics-apiserver"))
+		require.NotNil(t, findObj(objs, "APIService", "v1beta1.external.metrics.k8s.io"))
+	})
+
+	t.Run("values from spec", func(t *testing.T) {
+		level := v1alpha1.OperatorLogLevelDebug
+		metricsLevel := v1alpha1.MetricsServerLogLevelDebug
+		values, err := ValuesFromSpec(v1alpha1.KedaSpec{
+			Logging: &v1alpha1.LoggingCfg{
+				Operator:      &v1alpha1.LoggingOperatorCfg{Level: &level},
+				MetricsServer: &v1alpha1.LoggingMetricsSrvCfg{Level: &metricsLevel},
+			},
+			Resources: &v1alpha1.Resources{
+				Operator: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{
+						corev1.ResourceMemory: resource.MustParse("200Mi"),
+					},
+				},
+			},
+			Env: []corev1.EnvVar{
+				{Name: "test-env", Value: "test-value"},
+			},
+		})
+		require.NoError(t, err)
+
+		objs, err := Render(c, "keda", values)
+		require.NoError(t, err)
+
+		deployment := findObj(objs, "Deployment", "keda-manager")
+		require.NotNil(t, deployment)
+		require.Equal(t, "keda", deployment.GetNamespace())
+
+		containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+		container := containers[0].(map[string]interface{})
+		require.Contains(t, container["args"], "--zap-log-level=debug")
+		// format is not set in the spec, so the chart default is used
+		require.Contains(t, container["args"], "--zap-encoder=console")
+		// resources are merged with the chart defaults
+		require.Equal(t, map[string]interface{}{
+			"limits":   map[string]interface{}{"cpu": int64(1), "memory": "200Mi"},
+			"requests": map[string]interface{}{"cpu": "100m", "memory": "100Mi"},
+		}, container["resources"])
+		require.Contains(t, container["env"], map[string]interface{}{"name": "test-env", "value": "test-value"})
+
+		metricsServer := findObj(objs, "Deployment", "keda-manager-metrics-apiserver")
+		containers, _, _ = unstructured.NestedSlice(metricsServer.Object, "spec", "template", "spec", "containers")
+		container = containers[0].(map[string]interface{})
+		require.Contains(t, container["args"], "--v=4")
+	})
+
+	t.Run("monitoring values from spec", func(t *testing.T) {
+		for _, tt := range []struct {
+			mode     v1alpha1.MonitoringMode
+			monitors int
+		}{
+			{mode: v1alpha1.MonitoringModeAnnotations, monitors: 0},
+			{mode: v1alpha1.MonitoringModeMonitors, monitors: 2},
+		} {
+			mode := tt.mode
+			interval := "30s"
+			values, err := ValuesFromSpec(v1alpha1.KedaSpec{
+				Monitoring: &v1alpha1.Monitoring{Enabled: true, Mode: &mode, Interval: &interval},
+			})
+			require.NoError(t, err)
+
+			objs, err := Render(c, "keda", values)
+			require.NoError(t, err)
+			require.Len(t, objs, 15+tt.monitors)
+
+			metricsServer := findObj(objs, "Deployment", "keda-manager-metrics-apiserver")
+			annotations, _, _ := unstructured.NestedStringMap(metricsServer.Object, "spec", "template", "metadata", "annotations")
+			containers, _, _ := unstructured.NestedSlice(metricsServer.Object, "spec", "template", "spec", "containers")
+			// metrics are exposed in both modes, pods are annotated without monitors
+			require.Contains(t, containers[0].(map[string]interface{})["args"], "--metrics-port=9022")
+			require.Equal(t, tt.monitors == 0, annotations["prometheus.io/port"] == "9022")
+
+			serviceMonitor := findObj(objs, "ServiceMonitor", "keda-manager-metrics-apiserver")
+			if tt.monitors == 0 {
+				require.Nil(t, serviceMonitor)
+				continue
+			}
+			endpoints, _, _ := unstructured.NestedSlice(serviceMonitor.Object, "spec", "endpoints")
+			require.Equal(t, map[string]interface{}{
+				"port":     "metrics",
+				"path":     "/metrics",
+				"interval": "30s",
+			}, endpoints[0])
+			require.NotNil(t, findObj(objs, "PodMonitor", "keda-manager"))
+		}
+	})
+
+	t.Run("pod identity values from spec", func(t *testing.T) {
+		values, err := ValuesFromSpec(v1alpha1.KedaSpec{
+			PodIdentity: &v1alpha1.PodIdentityCfg{
+				Provider: v1alpha1.PodIdentityProviderGCP,
+				GCP:      &v1alpha1.GCPIdentityCfg{ServiceAccount: "keda@test.iam.gserviceaccount.com"},
+			},
+		})
+		require.NoError(t, err)
+
+		objs, err := Render(c, "keda", values)
+		require.NoError(t, err)
+
+		serviceAccount := findObj(objs, "ServiceAccount", "keda-manager")
+		require.Equal(t, map[string]string{
+			"iam.gke.io/gcp-service-account": "keda@test.iam.gserviceaccount.com",
+		}, serviceAccount.GetAnnotations())
+	})
+
+	t.Run("security values from spec", func(t *testing.T) {
+		readOnly := true
+		automount := false
+		priorityClassName := "keda"
+		var fsGroup int64 = 1000
+		values, err := ValuesFromSpec(v1alpha1.KedaSpec{
+			Security: &v1alpha1.SecurityCfg{
+				FSGroup:                      &fsGroup,
+				ReadOnlyRootFilesystem:       &readOnly,
+				SeccompProfile:               &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+				PriorityClassName:            &priorityClassName,
+				AutomountServiceAccountToken: &automount,
+			},
+		})
+		require.NoError(t, err)
+
+		// values files must not restore the removed chart defaults
+		objs, err := Render(c, "keda", MergeValues(values, map[string]interface{}{
+			"securityContext": map[string]interface{}{
+				"metricServer": map[string]interface{}{
+					"seccompProfile": map[string]interface{}{"type": "RuntimeDefault"},
+				},
+			},
+		}))
+		require.NoError(t, err)
+
+		metricsServer := findObj(objs, "Deployment", "keda-manager-metrics-apiserver")
+		podSpec, _, _ := unstructured.NestedMap(metricsServer.Object, "spec", "template", "spec")
+		require.Equal(t, "keda", podSpec["priorityClassName"])
+		require.Equal(t, false, podSpec["automountServiceAccountToken"])
+		require.Equal(t, map[string]interface{}{
+			"runAsNonRoot":   true,
+			"fsGroup":        int64(1000),
+			"seccompProfile": map[string]interface{}{"type": "RuntimeDefault"},
+		}, podSpec["securityContext"])
+		require.Len(t, podSpec["volumes"], 2)
+
+		container := podSpec["containers"].([]interface{})[0].(map[string]interface{})
+		securityContext := container["securityContext"].(map[string]interface{})
+		require.Equal(t, true, securityContext["readOnlyRootFilesystem"])
+		require.NotContains(t, securityContext, "seccompProfile")
+		require.Len(t, container["volumeMounts"], 2)
+
+		serviceAccount := findObj(objs, "ServiceAccount", "keda-manager")
+		require.Equal(t, false, serviceAccount.Object["automountServiceAccountToken"])
+	})
+
+	t.Run("chart values", func(t *testing.T) {
+		values := MergeValues(
+			map[string]interface{}{
+				"prometheus": map[string]interface{}{
+					"operator": map[string]interface{}{"enabled": true},
+				},
+			},
+			map[string]interface{}{
+				"prometheus": map[string]interface{}{
+					"operator": map[string]interface{}{"enabled": false, "port": 8888},
+				},
+			},
+		)
+
+		objs, err := Render(c, "kyma-system", values)
+		require.NoError(t, err)
+
+		deployment := findObj(objs, "Deployment", "keda-manager")
+		annotations, _, _ := unstructured.NestedStringMap(deployment.Object, "spec", "template", "metadata", "annotations")
+		require.Equal(t, "true", annotations["prometheus.io/scrape"])
+		require.Equal(t, "8888", annotations["prometheus.io/port"])
+	})
+}
+
+func Test_Renderer(t *testing.T) {
+	c, err := LoadFS(os.DirFS(testChartPath))
+	require.NoError(t, err)
+
+	r := NewRenderer(c, map[string]interface{}{
+		"logging": map[string]interface{}{
+			"operator": map[string]interface{}{"level": "error", "format": "json"},
+		},
+	})
+
+	level := v1alpha1.OperatorLogLevelDebug
+	objs, err := r.Render(v1alpha1.KedaSpec{
+		Logging: &v1alpha1.LoggingCfg{
+			Operator: &v1alpha1.LoggingOperatorCfg{Level: &level},
+		},
+	}, "keda")
+	require.NoError(t, err)
+
+	deployment := findObj(objs, "Deployment", "keda-manager")
+	require.NotNil(t, deployment)
+	require.Equal(t, "keda", deployment.GetNamespace())
+
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	container := containers[0].(map[string]interface{})
+	// values of the instance take precedence over the given values
+	require.Contains(t, container["args"], "--zap-log-level=debug")
+	require.Contains(t, container["args"], "--zap-encoder=json")
+}
+
+func Test_Manifest(t *testing.T) {
+	c, err := Load(testChartPath)
+	require.NoError(t, err)
+
+	objs, err := Render(c, "kyma-system", nil)
+	require.NoError(t, err)
+
+	file, err := os.Open("../../keda-manager.yaml")
+	require.NoError(t, err)
+	defer file.Close()
+
+	manifest, err := yaml.LoadData(file)
+	require.NoError(t, err)
+
+	// the manifest is generated from the chart with make keda-manifest
+	require.Equal(t, objs, manifest)
+}
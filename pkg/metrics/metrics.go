@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// States are the states of the module reported by the state gauge
+	States = []string{"Ready", "Processing", "Error"}
+
+	// DriftedFields counts fields of keda components changed by other
+	// actors and reverted by the manager
+	DriftedFields = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "keda_manager_drifted_fields_total",
+			Help: "Number of fields of keda components changed by other actors.",
+		},
+		[]string{"kind", "manager"},
+	)
+
+	stateFnDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "keda_manager_state_fn_duration_seconds",
+			Help:    "Duration of the reconciler state functions.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+		},
+		[]string{"state_fn"},
+	)
+
+	applyErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "keda_manager_apply_errors_total",
+			Help: "Number of errors applying keda components.",
+		},
+		[]string{"group", "version", "kind"},
+	)
+
+	state = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "keda_manager_state",
+			Help: "State of the Keda instance; the gauge of the current state is 1.",
+		},
+		[]string{"namespace", "name", "state"},
+	)
+
+	verifyLatency = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "keda_manager_verify_latency_seconds",
+			Help:    "Time from the start of the installation until keda components are verified ready.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		},
+	)
+
+	managedObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "keda_manager_managed_objects",
+			Help: "Number of keda components applied by the manager for the Keda instance.",
+		},
+		[]string{"namespace", "name"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		DriftedFields,
+		stateFnDuration,
+		applyErrors,
+		state,
+		verifyLatency,
+		managedObjects,
+	)
+}
+
+func ObserveStateFnDuration(stateFnName string, d time.Duration) {
+	stateFnDuration.WithLabelValues(stateFnName).Observe(d.Seconds())
+}
+
+func IncApplyErrors(gvk schema.GroupVersionKind) {
+	applyErrors.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind).Inc()
+}
+
+// SetState sets the gauge of the current state of the instance and resets others
+func SetState(namespace, name, current string) {
+	for _, s := range States {
+		var value float64
+		if s == current {
+			value = 1
+		}
+		state.WithLabelValues(namespace, name, s).Set(value)
+	}
+}
+
+// DeleteState removes state gauges of the deleted instance
+func DeleteState(namespace, name string) {
+	for _, s := range States {
+		state.DeleteLabelValues(namespace, name, s)
+	}
+}
+
+func ObserveVerifyLatency(d time.Duration) {
+	verifyLatency.Observe(d.Seconds())
+}
+
+func SetManagedObjects(namespace, name string, count int) {
+	managedObjects.WithLabelValues(namespace, name).Set(float64(count))
+}
+
+// DeleteManagedObjects removes the managed objects gauge of the deleted instance
+func DeleteManagedObjects(namespace, name string) {
+	managedObjects.DeleteLabelValues(namespace, name)
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestSetState(t *testing.T) {
+	SetState("kyma-system", "default", "Processing")
+	SetState("kyma-system", "default", "Ready")
+
+	require.Equal(t, float64(1), testutil.ToFloat64(state.WithLabelValues("kyma-system", "default", "Ready")))
+	require.Equal(t, float64(0), testutil.ToFloat64(state.WithLabelValues("kyma-system", "default", "Processing")))
+	require.Equal(t, float64(0), testutil.ToFloat64(state.WithLabelValues("kyma-system", "default", "Error")))
+
+	DeleteState("kyma-system", "default")
+	require.Equal(t, 0, testutil.CollectAndCount(state))
+}
+
+func TestIncApplyErrors(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	IncApplyErrors(gvk)
+	IncApplyErrors(gvk)
+
+	require.Equal(t, float64(2), testutil.ToFloat64(applyErrors.WithLabelValues("apps", "v1", "Deployment")))
+}
+
+func TestSetManagedObjects(t *testing.T) {
+	SetManagedObjects("kyma-system", "default", 10)
+	SetManagedObjects("kyma-system", "other", 12)
+
+	require.Equal(t, float64(10), testutil.ToFloat64(managedObjects.WithLabelValues("kyma-system", "default")))
+
+	DeleteManagedObjects("kyma-system", "default")
+	DeleteManagedObjects("kyma-system", "other")
+	require.Equal(t, 0, testutil.CollectAndCount(managedObjects))
+}
@@ -0,0 +1,230 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// verbs needed to apply, watch and delete managed objects
+	manageVerbs = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+
+	// rules of controller-runtime itself, not related to managed objects
+	runtimeRules = []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"events"},
+			Verbs:     []string{"create", "patch"},
+		},
+		{
+			APIGroups: []string{"coordination.k8s.io"},
+			Resources: []string{"leases"},
+			Verbs:     manageVerbs,
+		},
+	}
+)
+
+// resource returns the resource name of given kind
+func resource(kind string) string {
+	name := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(name, "y"):
+		return strings.TrimSuffix(name, "y") + "ies"
+	case strings.HasSuffix(name, "s"):
+		return name + "es"
+	default:
+		return name + "s"
+	}
+}
+
+// roleRules grants permissions to create given roles and bind given roles
+// without holding permissions of these roles; names are known to the
+// authorizer because objects are applied with server-side apply
+func roleRules(resource string, escalate, bind map[string]struct{}) []rbacv1.PolicyRule {
+	var result []rbacv1.PolicyRule
+	if len(escalate) > 0 {
+		result = append(result, rbacv1.PolicyRule{
+			APIGroups:     []string{rbacv1.GroupName},
+			Resources:     []string{resource},
+			Verbs:         []string{"escalate"},
+			ResourceNames: sortedKeys(escalate),
+		})
+	}
+	if len(bind) > 0 {
+		result = append(result, rbacv1.PolicyRule{
+			APIGroups:     []string{rbacv1.GroupName},
+			Resources:     []string{resource},
+			Verbs:         []string{"bind"},
+			ResourceNames: sortedKeys(bind),
+		})
+	}
+	return result
+}
+
+// Rules returns rules keda-manager needs to manage given objects
+func Rules(objs []unstructured.Unstructured) ([]rbacv1.PolicyRule, error) {
+	resources := map[string]map[string]struct{}{}
+	escalate := map[string]map[string]struct{}{
+		"roles":        {},
+		"clusterroles": {},
+	}
+	bind := map[string]map[string]struct{}{
+		"roles":        {},
+		"clusterroles": {},
+	}
+
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if _, found := resources[gvk.Group]; !found {
+			resources[gvk.Group] = map[string]struct{}{}
+		}
+		resources[gvk.Group][resource(gvk.Kind)] = struct{}{}
+
+		if gvk.Group != rbacv1.GroupName {
+			continue
+		}
+
+		switch gvk.Kind {
+		case "Role", "ClusterRole":
+			escalate[resource(gvk.Kind)][obj.GetName()] = struct{}{}
+		case "RoleBinding", "ClusterRoleBinding":
+			kind, _, err := unstructured.NestedString(obj.Object, "roleRef", "kind")
+			if err != nil {
+				return nil, err
+			}
+			name, _, err := unstructured.NestedString(obj.Object, "roleRef", "name")
+			if err != nil {
+				return nil, err
+			}
+			if _, found := bind[resource(kind)]; !found {
+				return nil, fmt.Errorf("invalid role reference of %s/%s: %s", gvk.Kind, obj.GetName(), kind)
+			}
+			bind[resource(kind)][name] = struct{}{}
+		}
+	}
+
+	var result []rbacv1.PolicyRule
+	for _, group := range sortedKeys(resources) {
+		result = append(result, rbacv1.PolicyRule{
+			APIGroups: []string{group},
+			Resources: sortedKeys(resources[group]),
+			Verbs:     manageVerbs,
+		})
+	}
+
+	result = append(result, roleRules("clusterroles", escalate["clusterroles"], bind["clusterroles"])...)
+	result = append(result, roleRules("roles", escalate["roles"], bind["roles"])...)
+	return append(result, runtimeRules...), nil
+}
+
+var (
+	unquotedMarkerValue = regexp.MustCompile(`^[a-zA-Z0-9./*-]+$`)
+)
+
+// markerValues joins given values of a marker argument, values
+// which can't be parsed as they are, e.g. empty ones, are quoted
+func markerValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = value
+		if !unquotedMarkerValue.MatchString(value) {
+			quoted[i] = strconv.Quote(value)
+		}
+	}
+	return strings.Join(quoted, ";")
+}
+
+// Markers returns kubebuilder markers granting given rules
+func Markers(rules []rbacv1.PolicyRule) []string {
+	var result []string
+	for _, rule := range rules {
+		marker := fmt.Sprintf("//+kubebuilder:rbac:groups=%s,resources=%s,verbs=%s",
+			markerValues(rule.APIGroups),
+			markerValues(rule.Resources),
+			markerValues(rule.Verbs),
+		)
+		if len(rule.ResourceNames) > 0 {
+			marker += ",resourceNames=" + markerValues(rule.ResourceNames)
+		}
+		result = append(result, marker)
+	}
+	return result
+}
+
+// resourceAttributes returns attributes of every single permission granted by given rule
+func resourceAttributes(rule rbacv1.PolicyRule) []authorizationv1.ResourceAttributes {
+	names := rule.ResourceNames
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	var result []authorizationv1.ResourceAttributes
+	for _, group := range rule.APIGroups {
+		for _, resource := range rule.Resources {
+			for _, verb := range rule.Verbs {
+				for _, name := range names {
+					result = append(result, authorizationv1.ResourceAttributes{
+						Group:    group,
+						Resource: resource,
+						Verb:     verb,
+						Name:     name,
+					})
+				}
+			}
+		}
+	}
+	return result
+}
+
+func permission(attributes authorizationv1.ResourceAttributes) string {
+	result := fmt.Sprintf("%s %s", attributes.Verb, schema.GroupResource{
+		Group:    attributes.Group,
+		Resource: attributes.Resource,
+	})
+	if attributes.Name != "" {
+		result += "/" + attributes.Name
+	}
+	return result
+}
+
+// MissingPermissions checks given rules with self subject access reviews
+// and returns permissions which are not granted, e.g. "create deployments.apps"
+func MissingPermissions(ctx context.Context, c client.Client, rules []rbacv1.PolicyRule) ([]string, error) {
+	var result []string
+	for _, rule := range rules {
+		for _, attributes := range resourceAttributes(rule) {
+			attributes := attributes
+			review := authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &attributes,
+				},
+			}
+			if err := c.Create(ctx, &review); err != nil {
+				return nil, fmt.Errorf("unable to review %s permission: %w", permission(attributes), err)
+			}
+			if !review.Status.Allowed {
+				result = append(result, permission(attributes))
+			}
+		}
+	}
+	return result, nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,133 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testObj(apiVersion, kind, name string) unstructured.Unstructured {
+	var u unstructured.Unstructured
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetName(name)
+	return u
+}
+
+func testBinding(kind, name, roleKind, roleName string) unstructured.Unstructured {
+	u := testObj("rbac.authorization.k8s.io/v1", kind, name)
+	u.Object["roleRef"] = map[string]interface{}{
+		"apiGroup": rbacv1.GroupName,
+		"kind":     roleKind,
+		"name":     roleName,
+	}
+	return u
+}
+
+func Test_resource(t *testing.T) {
+	require.Equal(t, "deployments", resource("Deployment"))
+	require.Equal(t, "networkpolicies", resource("NetworkPolicy"))
+	require.Equal(t, "apiservices", resource("APIService"))
+	require.Equal(t, "ingresses", resource("Ingress"))
+}
+
+func TestRules(t *testing.T) {
+	rules, err := Rules([]unstructured.Unstructured{
+		testObj("apps/v1", "Deployment", "keda-manager"),
+		testObj("v1", "Service", "keda-manager-metrics-apiserver"),
+		testObj("v1", "ServiceAccount", "keda-manager"),
+		testObj("rbac.authorization.k8s.io/v1", "ClusterRole", "keda-manager"),
+		testBinding("ClusterRoleBinding", "keda-manager", "ClusterRole", "keda-manager"),
+		testBinding("ClusterRoleBinding", "keda-manager-auth-delegator", "ClusterRole", "system:auth-delegator"),
+		testBinding("RoleBinding", "keda-manager-auth-reader", "Role", "extension-apiserver-authentication-reader"),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"serviceaccounts", "services"}, Verbs: manageVerbs},
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: manageVerbs},
+		{APIGroups: []string{"rbac.authorization.k8s.io"}, Resources: []string{"clusterrolebindings", "clusterroles", "rolebindings"}, Verbs: manageVerbs},
+		{
+			APIGroups:     []string{"rbac.authorization.k8s.io"},
+			Resources:     []string{"clusterroles"},
+			Verbs:         []string{"escalate"},
+			ResourceNames: []string{"keda-manager"},
+		},
+		{
+			APIGroups:     []string{"rbac.authorization.k8s.io"},
+			Resources:     []string{"clusterroles"},
+			Verbs:         []string{"bind"},
+			ResourceNames: []string{"keda-manager", "system:auth-delegator"},
+		},
+		{
+			APIGroups:     []string{"rbac.authorization.k8s.io"},
+			Resources:     []string{"roles"},
+			Verbs:         []string{"bind"},
+			ResourceNames: []string{"extension-apiserver-authentication-reader"},
+		},
+	}, rules[:len(rules)-len(runtimeRules)])
+
+	t.Run("invalid role reference", func(t *testing.T) {
+		_, err := Rules([]unstructured.Unstructured{
+			testBinding("RoleBinding", "test", "Group", "test"),
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestMarkers(t *testing.T) {
+	markers := Markers([]rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"secrets", "services"}, Verbs: []string{"get", "list"}},
+		{
+			APIGroups:     []string{"rbac.authorization.k8s.io"},
+			Resources:     []string{"clusterroles"},
+			Verbs:         []string{"bind"},
+			ResourceNames: []string{"keda-manager", "system:auth-delegator"},
+		},
+	})
+
+	require.Equal(t, []string{
+		`//+kubebuilder:rbac:groups="",resources=secrets;services,verbs=get;list`,
+		`//+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=bind,resourceNames=keda-manager;"system:auth-delegator"`,
+	}, markers)
+}
+
+// reviewClient allows permissions for given verbs only
+type reviewClient struct {
+	client.Client
+	allowed map[string]bool
+}
+
+func (c *reviewClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	review := obj.(*authorizationv1.SelfSubjectAccessReview)
+	review.Status.Allowed = c.allowed[review.Spec.ResourceAttributes.Verb]
+	return nil
+}
+
+func TestMissingPermissions(t *testing.T) {
+	c := &reviewClient{
+		Client:  fake.NewClientBuilder().Build(),
+		allowed: map[string]bool{"get": true, "bind": true},
+	}
+
+	missing, err := MissingPermissions(context.Background(), c, []rbacv1.PolicyRule{
+		{APIGroups: []string{"apps"}, Resources: []string{"deployments"}, Verbs: []string{"get", "create"}},
+		{
+			APIGroups:     []string{"rbac.authorization.k8s.io"},
+			Resources:     []string{"clusterroles"},
+			Verbs:         []string{"escalate", "bind"},
+			ResourceNames: []string{"keda-manager"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"create deployments.apps",
+		"escalate clusterroles.rbac.authorization.k8s.io/keda-manager",
+	}, missing)
+}
@@ -3,47 +3,203 @@ package reconciler
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/crypto/sha256"
+	"github.com/kyma-project/keda-manager/pkg/metrics"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	fieldManager = "keda-manager"
+
+	// the appliedHashAnnotation keeps the checksum of the applied content
+	appliedHashAnnotation = "keda-manager.kyma-project.io/applied-hash"
+
+	// bounds patches sent to the api server at a time
+	maxConcurrentApplies = 8
+)
+
 var (
 	InstallationErr = errors.New("installation error")
 )
 
+func (s *systemState) setLiveObj(desired, live unstructured.Unstructured) {
+	if s.live == nil {
+		s.live = map[string]unstructured.Unstructured{}
+	}
+	s.live[objKey(desired)] = live
+}
+
+// setAppliedHash stamps the desired object with the checksum of its content
+func setAppliedHash(u *unstructured.Unstructured) error {
+	hash, err := sha256.DefaultCalculator.CalculateContentSum(*u)
+	if err != nil {
+		return err
+	}
+
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[appliedHashAnnotation] = hash
+	u.SetAnnotations(annotations)
+	return nil
+}
+
+// unchangedLiveObj returns the live object, if it was applied with the content
+// of the desired object and its fields were not changed since then
+func (s *systemState) unchangedLiveObj(desired unstructured.Unstructured) (unstructured.Unstructured, bool) {
+	live, found := s.live[objKey(desired)]
+	if !found {
+		return unstructured.Unstructured{}, false
+	}
+
+	hash := desired.GetAnnotations()[appliedHashAnnotation]
+	if hash == "" || live.GetAnnotations()[appliedHashAnnotation] != hash {
+		return unstructured.Unstructured{}, false
+	}
+	// fields may be removed by other actors without taking their ownership
+	if !valuesMatch(withoutIgnoredFields(desired.Object), withoutIgnoredFields(live.Object)) {
+		return unstructured.Unstructured{}, false
+	}
+	return live, true
+}
+
+// applyWave returns the position of the object in the apply order; objects
+// of one wave do not depend on each other and are applied in parallel
+func applyWave(u unstructured.Unstructured) int {
+	switch u.GetKind() {
+	case "CustomResourceDefinition", "Namespace":
+		return 0
+	case "ServiceAccount", "ClusterRole", "Role", "ClusterRoleBinding", "RoleBinding":
+		return 1
+	// registered once the services they refer to are served
+	case "APIService", "ValidatingWebhookConfiguration", "MutatingWebhookConfiguration":
+		return 3
+	default:
+		return 2
+	}
+}
+
+// applyWaves groups indexes of objects by their waves, keeping their order
+func applyWaves(objs []unstructured.Unstructured) [][]int {
+	var result [][]int
+	for i, obj := range objs {
+		wave := applyWave(obj)
+		for len(result) <= wave {
+			result = append(result, nil)
+		}
+		result[wave] = append(result[wave], i)
+	}
+	return result
+}
+
+type applyResult struct {
+	obj     unstructured.Unstructured
+	skipped bool
+	err     error
+}
+
+func applyObj(ctx context.Context, r *fsm, s *systemState, obj unstructured.Unstructured) applyResult {
+	span := trace.SpanFromContext(ctx)
+	if err := setAppliedHash(&obj); err != nil {
+		r.log.With("err", err).With("name", obj.GetName()).Warn("unable to calculate applied hash")
+	}
+
+	if live, unchanged := s.unchangedLiveObj(obj); unchanged {
+		span.AddEvent("skip", objAttributes(obj))
+		return applyResult{obj: live, skipped: true}
+	}
+
+	span.AddEvent("apply", objAttributes(obj))
+	r.log.
+		With("gvk", obj.GetObjectKind().GroupVersionKind()).
+		With("name", obj.GetName()).
+		With("ns", obj.GetNamespace()).
+		Debug("applying")
+
+	err := r.Patch(ctx, &obj, client.Apply, &client.PatchOptions{
+		Force:        pointer.Bool(true),
+		FieldManager: fieldManager,
+	})
+	return applyResult{obj: obj, err: err}
+}
+
+// applyParallel applies given objects with at most maxConcurrentApplies
+// patches at a time; results have the order of the objects
+func applyParallel(ctx context.Context, r *fsm, s *systemState, objs []unstructured.Unstructured) []applyResult {
+	results := make([]applyResult, len(objs))
+	semaphore := make(chan struct{}, maxConcurrentApplies)
+
+	var wg sync.WaitGroup
+	for i := range objs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			results[i] = applyObj(ctx, r, s, objs[i])
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
 func sFnApply(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
-	var isError bool
-	for _, obj := range r.Objs {
-		r.log.
-			With("gvk", obj.GetObjectKind().GroupVersionKind()).
-			With("name", obj.GetName()).
-			With("ns", obj.GetNamespace()).
-			Debug("applying")
-
-		err := r.Patch(ctx, &obj, client.Apply, &client.PatchOptions{
-			Force:        pointer.Bool(true),
-			FieldManager: "keda-manager",
-		})
-
-		if err != nil {
-			r.log.With("err", err).Error("apply error")
-			isError = true
+	if err := removeDryRunResult(ctx, r, s); err != nil {
+		r.log.With("err", err).Warn("unable to remove dry run result")
+	}
+
+	span := trace.SpanFromContext(ctx)
+	var failures []string
+	for _, wave := range applyWaves(r.Objs) {
+		objs := make([]unstructured.Unstructured, 0, len(wave))
+		for _, i := range wave {
+			objs = append(objs, r.Objs[i])
 		}
 
-		s.objs = append(s.objs, obj)
+		for _, result := range applyParallel(ctx, r, s, objs) {
+			obj := result.obj
+			switch {
+			case result.skipped:
+			case result.err != nil:
+				r.log.With("err", result.err).With("name", obj.GetName()).Error("apply error")
+				metrics.IncApplyErrors(obj.GroupVersionKind())
+				span.RecordError(result.err, objAttributes(obj))
+				s.events.failed(EventReasonApplyFailed, obj, result.err)
+				failures = append(failures, fmt.Sprintf("%s %s: %s",
+					obj.GetKind(), client.ObjectKeyFromObject(&obj), result.err))
+			default:
+				s.events.applied(obj)
+			}
+			s.objs = append(s.objs, obj)
+		}
+
+		// objects of next waves depend on the failed ones
+		if len(failures) != 0 {
+			break
+		}
 	}
+	metrics.SetManagedObjects(s.instance.Namespace, s.instance.Name, len(s.objs))
 	// no errors
-	if !isError {
-		return switchState(sFnVerify)
+	if len(failures) == 0 {
+		return switchState(sFnPruneTargetNamespace)
 	}
 
 	s.instance.UpdateStateFromErr(
 		v1alpha1.ConditionTypeInstalled,
 		v1alpha1.ConditionReasonApplyObjError,
-		InstallationErr,
+		fmt.Errorf("%w: %s", InstallationErr, strings.Join(failures, "; ")),
 	)
 	return stopWithNoRequeue()
 }
@@ -0,0 +1,201 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// applyCountingClient counts patches and emulates the server-side apply
+// the fake client does not support
+type applyCountingClient struct {
+	client.Client
+	patches int64
+	// fails patches of objects with given names
+	failing map[string]error
+}
+
+func (c *applyCountingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch != client.Apply {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	atomic.AddInt64(&c.patches, 1)
+	if err, found := c.failing[obj.GetName()]; found {
+		return err
+	}
+
+	u := obj.(*unstructured.Unstructured)
+	var live unstructured.Unstructured
+	live.SetGroupVersionKind(u.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKeyFromObject(u), &live)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err != nil {
+		return c.Create(ctx, u)
+	}
+	u.SetResourceVersion(live.GetResourceVersion())
+	return c.Update(ctx, u)
+}
+
+func testApplyObjs(n int, image string) []unstructured.Unstructured {
+	objs := make([]unstructured.Unstructured, 0, n)
+	for i := 0; i < n; i++ {
+		obj := testValidateObj("apps/v1", "Deployment", fmt.Sprintf("keda-%d", i))
+		_ = unstructured.SetNestedSlice(obj.Object, []interface{}{
+			map[string]interface{}{"name": "keda", "image": image},
+		}, "spec", "template", "spec", "containers")
+		objs = append(objs, obj)
+	}
+	return objs
+}
+
+// testApply runs the drift detection and the apply of given objects, and
+// returns the number of patches
+func testApply(c *applyCountingClient, objs []unstructured.Unstructured) (int, error) {
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{Objs: make([]unstructured.Unstructured, len(objs))},
+		K8s: K8s{
+			Client:        c,
+			EventRecorder: record.NewFakeRecorder(100),
+		},
+	}
+	for i := range objs {
+		objs[i].DeepCopyInto(&r.Objs[i])
+	}
+
+	before := atomic.LoadInt64(&c.patches)
+	s := &systemState{}
+	if _, _, err := sFnDetectDrift(context.Background(), r, s); err != nil {
+		return 0, err
+	}
+	if _, _, err := sFnApply(context.Background(), r, s); err != nil {
+		return 0, err
+	}
+	if len(s.objs) != len(objs) {
+		return 0, fmt.Errorf("%d objects applied, expected %d", len(s.objs), len(objs))
+	}
+	return int(atomic.LoadInt64(&c.patches) - before), nil
+}
+
+func Test_sFnApply_appliedHash(t *testing.T) {
+	c := &applyCountingClient{Client: fake.NewClientBuilder().Build()}
+
+	patches, err := testApply(c, testApplyObjs(3, "keda:2.8.0"))
+	require.NoError(t, err)
+	require.Equal(t, 3, patches)
+
+	// unchanged objects are not patched
+	patches, err = testApply(c, testApplyObjs(3, "keda:2.8.0"))
+	require.NoError(t, err)
+	require.Equal(t, 0, patches)
+
+	// changed objects are patched
+	patches, err = testApply(c, testApplyObjs(3, "keda:2.9.0"))
+	require.NoError(t, err)
+	require.Equal(t, 3, patches)
+
+	// fields removed by other actors are restored
+	var live unstructured.Unstructured
+	live.SetGroupVersionKind(testApplyObjs(1, "")[0].GroupVersionKind())
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "kyma-system", Name: "keda-0"}, &live))
+	unstructured.RemoveNestedField(live.Object, "spec", "template")
+	require.NoError(t, c.Update(context.Background(), &live))
+
+	patches, err = testApply(c, testApplyObjs(3, "keda:2.9.0"))
+	require.NoError(t, err)
+	require.Equal(t, 1, patches)
+}
+
+func benchmarkApply(b *testing.B, image func(i int) string) {
+	c := &applyCountingClient{Client: fake.NewClientBuilder().Build()}
+	if _, err := testApply(c, testApplyObjs(50, image(-1))); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	var patches int
+	for i := 0; i < b.N; i++ {
+		n, err := testApply(c, testApplyObjs(50, image(i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		patches += n
+	}
+	b.ReportMetric(float64(patches)/float64(b.N), "patches/op")
+}
+
+// BenchmarkApply_unchanged reconciles objects which did not change; no
+// patches are sent to the api server
+func BenchmarkApply_unchanged(b *testing.B) {
+	benchmarkApply(b, func(int) string { return "keda:2.8.0" })
+}
+
+// BenchmarkApply_changed reconciles objects which changed since the last
+// reconciliation; all objects are patched
+func BenchmarkApply_changed(b *testing.B) {
+	benchmarkApply(b, func(i int) string { return fmt.Sprintf("keda:2.8.%d", i+1) })
+}
+
+func Test_applyWaves(t *testing.T) {
+	objs := []unstructured.Unstructured{
+		testValidateObj("apiregistration.k8s.io/v1", "APIService", "v1beta1.external.metrics.k8s.io"),
+		testValidateObj("apps/v1", "Deployment", operatorName),
+		testValidateObj("v1", "ServiceAccount", operatorName),
+		testValidateObj("apiextensions.k8s.io/v1", "CustomResourceDefinition", "scaledobjects.keda.sh"),
+		testValidateObj("v1", "Service", operatorName),
+		testValidateObj("rbac.authorization.k8s.io/v1", "ClusterRole", operatorName),
+	}
+	require.Equal(t, [][]int{{3}, {2, 5}, {1, 4}, {0}}, applyWaves(objs))
+}
+
+func Test_sFnApply_failures(t *testing.T) {
+	objs := []unstructured.Unstructured{
+		testValidateObj("v1", "ServiceAccount", "keda-0"),
+		testValidateObj("v1", "ServiceAccount", "keda-1"),
+		testValidateObj("v1", "ServiceAccount", "keda-2"),
+		testValidateObj("apps/v1", "Deployment", operatorName),
+	}
+	c := &applyCountingClient{
+		Client: fake.NewClientBuilder().Build(),
+		failing: map[string]error{
+			"keda-0": errors.New("forbidden"),
+			"keda-2": errors.New("conflict"),
+		},
+	}
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{Objs: objs},
+		K8s: K8s{
+			Client:        c,
+			EventRecorder: record.NewFakeRecorder(100),
+		},
+	}
+	s := &systemState{}
+
+	_, _, err := sFnApply(context.Background(), r, s)
+	require.NoError(t, err)
+
+	// all objects of the wave are applied, the next waves are not
+	require.Equal(t, int64(3), c.patches)
+	require.Len(t, s.objs, 3)
+	require.Len(t, s.events.failures, 2)
+
+	// failures are reported per object
+	require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+	require.Equal(t,
+		"installation error: ServiceAccount kyma-system/keda-0: forbidden; ServiceAccount kyma-system/keda-2: conflict",
+		s.instance.Status.Conditions[0].Message,
+	)
+}
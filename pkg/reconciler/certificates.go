@@ -0,0 +1,376 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/crypto/certs"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apirt "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	certificatesSecretName  = "keda-manager-metrics-apiserver-certs"
+	certificatesVolumeName  = "metrics-apiserver-certs"
+	certificatesMountPath   = "/certs"
+	certManagerAPIVersion   = "cert-manager.io/v1"
+	certManagerCAName       = "keda-manager-ca"
+	certManagerSelfSigned   = "keda-manager-selfsigned"
+	certManagerInjectCAFrom = "cert-manager.io/inject-ca-from"
+
+	IssuerKedaManager = "keda-manager"
+	IssuerCertManager = "cert-manager"
+
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+)
+
+var (
+	certManagerCRDs = []string{
+		"certificates.cert-manager.io",
+		"issuers.cert-manager.io",
+	}
+)
+
+func metricsServerDNSNames(namespace string) []string {
+	service := fmt.Sprintf("%s.%s", matricsServerName, namespace)
+	return []string{
+		matricsServerName,
+		service,
+		service + ".svc",
+		service + ".svc.cluster.local",
+	}
+}
+
+func isMetricsAPIService(u unstructured.Unstructured) bool {
+	return u.GetKind() == "APIService" && u.GetAPIVersion() == "apiregistration.k8s.io/v1"
+}
+
+func certificatesSecret(namespace string, b certs.Bundle) (unstructured.Unstructured, error) {
+	secret := corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      certificatesSecretName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of":    operatorName,
+				"app.kubernetes.io/managed-by": operatorName,
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			caCertKey:               b.CACert,
+			caKeyKey:                b.CAKey,
+			corev1.TLSCertKey:       b.Cert,
+			corev1.TLSPrivateKeyKey: b.Key,
+		},
+	}
+
+	obj, err := apirt.DefaultUnstructuredConverter.ToUnstructured(&secret)
+	return unstructured.Unstructured{Object: obj}, err
+}
+
+func certManagerObj(kind, name, namespace string, spec map[string]interface{}) unstructured.Unstructured {
+	u := unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": certManagerAPIVersion,
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": spec,
+		},
+	}
+	u.SetLabels(map[string]string{
+		"app.kubernetes.io/part-of":    operatorName,
+		"app.kubernetes.io/managed-by": operatorName,
+	})
+	return u
+}
+
+// certManagerObjs returns the self-signed CA issuer and the serving certificate issued by it
+func certManagerObjs(namespace string) []unstructured.Unstructured {
+	dnsNames := []interface{}{}
+	for _, name := range metricsServerDNSNames(namespace) {
+		dnsNames = append(dnsNames, name)
+	}
+
+	return []unstructured.Unstructured{
+		certManagerObj("Issuer", certManagerSelfSigned, namespace, map[string]interface{}{
+			"selfSigned": map[string]interface{}{},
+		}),
+		certManagerObj("Certificate", certManagerCAName, namespace, map[string]interface{}{
+			"isCA":       true,
+			"commonName": certManagerCAName,
+			"secretName": certManagerCAName,
+			"issuerRef": map[string]interface{}{
+				"kind": "Issuer",
+				"name": certManagerSelfSigned,
+			},
+		}),
+		certManagerObj("Issuer", certManagerCAName, namespace, map[string]interface{}{
+			"ca": map[string]interface{}{
+				"secretName": certManagerCAName,
+			},
+		}),
+		certManagerObj("Certificate", matricsServerName, namespace, map[string]interface{}{
+			"dnsNames":   dnsNames,
+			"secretName": certificatesSecretName,
+			"issuerRef": map[string]interface{}{
+				"kind": "Issuer",
+				"name": certManagerCAName,
+			},
+		}),
+	}
+}
+
+func secretObj(name, namespace string) unstructured.Unstructured {
+	var secret unstructured.Unstructured
+	secret.SetAPIVersion("v1")
+	secret.SetKind("Secret")
+	secret.SetName(name)
+	secret.SetNamespace(namespace)
+	return secret
+}
+
+// certificatesObjs returns all objects managing certificates, used to remove
+// them; secrets issued by cert-manager are not removed with their certificates
+func certificatesObjs(namespace string) []unstructured.Unstructured {
+	return append(certManagerObjs(namespace),
+		secretObj(certManagerCAName, namespace),
+		secretObj(certificatesSecretName, namespace),
+	)
+}
+
+// mountCertificates makes the metrics server serve certificates from given secret
+func mountCertificates(deployment *appsv1.Deployment, secretName string) error {
+	podSpec := &deployment.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: certificatesVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secretName,
+			},
+		},
+	})
+
+	container := &podSpec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      certificatesVolumeName,
+		MountPath: certificatesMountPath,
+		ReadOnly:  true,
+	})
+	container.Args = append(container.Args,
+		fmt.Sprintf("--tls-cert-file=%s/%s", certificatesMountPath, corev1.TLSCertKey),
+		fmt.Sprintf("--tls-private-key-file=%s/%s", certificatesMountPath, corev1.TLSPrivateKeyKey),
+	)
+	return nil
+}
+
+// trustCertificates makes the APIService verify the metrics server; the
+// CA bundle is injected by cert-manager if it is not given
+func trustCertificates(r *fsm, namespace string, caBundle []byte) error {
+	apiService, err := r.firstUnstructed(isMetricsAPIService)
+	if err != nil {
+		return err
+	}
+
+	unstructured.RemoveNestedField(apiService.Object, "spec", "insecureSkipTLSVerify")
+	if caBundle != nil {
+		return unstructured.SetNestedField(apiService.Object,
+			base64.StdEncoding.EncodeToString(caBundle), "spec", "caBundle")
+	}
+
+	annotations := apiService.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[certManagerInjectCAFrom] = fmt.Sprintf("%s/%s", namespace, matricsServerName)
+	apiService.SetAnnotations(annotations)
+	return nil
+}
+
+func loadBundle(ctx context.Context, c client.Client, namespace string) (certs.Bundle, error) {
+	var secret corev1.Secret
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: certificatesSecretName}, &secret)
+	if apierrors.IsNotFound(err) {
+		return certs.Bundle{}, nil
+	}
+	if err != nil {
+		return certs.Bundle{}, err
+	}
+
+	return certs.Bundle{
+		CACert: secret.Data[caCertKey],
+		CAKey:  secret.Data[caKeyKey],
+		Cert:   secret.Data[corev1.TLSCertKey],
+		Key:    secret.Data[corev1.TLSPrivateKeyKey],
+	}, nil
+}
+
+// selfManagedCertificates generates certificates of the metrics server and
+// renews them once they are due to renew
+func selfManagedCertificates(ctx context.Context, r *fsm, s *systemState) error {
+	current, err := loadBundle(ctx, r.Client, s.namespace)
+	if err != nil {
+		return err
+	}
+
+	// dry run neither generates nor reports certificates, the current
+	// ones are trusted to diff the APIService only
+	if r.isDryRun(&s.instance) {
+		if len(current.CACert) == 0 {
+			return nil
+		}
+		return trustCertificates(r, s.namespace, current.CACert)
+	}
+
+	now := time.Now()
+	bundle, err := certs.Ensure(current, metricsServerDNSNames(s.namespace), now)
+	if err != nil {
+		return err
+	}
+
+	secret, err := certificatesSecret(s.namespace, bundle)
+	if err != nil {
+		return err
+	}
+	r.Objs = append(r.Objs, secret)
+
+	if err := trustCertificates(r, s.namespace, bundle.CACert); err != nil {
+		return err
+	}
+
+	cert, err := certs.ParseCert(bundle.Cert)
+	if err != nil {
+		return err
+	}
+	ca, err := certs.ParseCert(bundle.CACert)
+	if err != nil {
+		return err
+	}
+
+	renewAt := certs.RenewAt(cert)
+	if caRenewAt := certs.RenewAt(ca); caRenewAt.Before(renewAt) {
+		renewAt = caRenewAt
+	}
+	s.requeueWithin(renewAt.Sub(now))
+
+	s.instance.Status.Certificates = &v1alpha1.CertificatesStatus{
+		Issuer:   IssuerKedaManager,
+		NotAfter: &metav1.Time{Time: cert.NotAfter},
+	}
+	return nil
+}
+
+// certManagerCertificates delegates issuing and renewing certificates of the
+// metrics server to cert-manager
+func certManagerCertificates(ctx context.Context, r *fsm, s *systemState) error {
+	r.Objs = append(r.Objs, certManagerObjs(s.namespace)...)
+	if err := trustCertificates(r, s.namespace, nil); err != nil {
+		return err
+	}
+
+	status := v1alpha1.CertificatesStatus{Issuer: IssuerCertManager}
+
+	var certificate unstructured.Unstructured
+	certificate.SetAPIVersion(certManagerAPIVersion)
+	certificate.SetKind("Certificate")
+	err := r.Get(ctx, types.NamespacedName{Namespace: s.namespace, Name: matricsServerName}, &certificate)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	notAfter, _, _ := unstructured.NestedString(certificate.Object, "status", "notAfter")
+	if notAfter != "" {
+		t, err := time.Parse(time.RFC3339, notAfter)
+		if err != nil {
+			return err
+		}
+		status.NotAfter = &metav1.Time{Time: t}
+	}
+
+	s.instance.Status.Certificates = &status
+	return nil
+}
+
+// pruneCertManagerObjs removes objects of cert-manager, e.g. once it is
+// uninstalled or not used anymore, as certificates are issued by the manager
+func pruneCertManagerObjs(ctx context.Context, r *fsm, s *systemState) error {
+	// dry run must not mutate the cluster
+	if r.isDryRun(&s.instance) {
+		return nil
+	}
+	objs := append(certManagerObjs(s.namespace), secretObj(certManagerCAName, s.namespace))
+	return deleteGeneratedObjs(ctx, r, s, objs, EventReasonPruned)
+}
+
+// sFnUpdateCertificates provides certificates of the metrics server
+// and removes them once they are not used anymore
+func sFnUpdateCertificates(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	cfg := s.instance.Spec.Certificates
+	if cfg == nil || !cfg.Enabled {
+		// certificates are reported in status once they are created;
+		// dry run must not mutate the cluster
+		if s.instance.Status.Certificates == nil || r.isDryRun(&s.instance) {
+			return switchState(sFnUpdateTriggerAuthentications)
+		}
+		if err := deleteGeneratedObjs(ctx, r, s, certificatesObjs(s.namespace), EventReasonPruned); err != nil {
+			return stopWithCertificatesErr(s, err)
+		}
+		s.instance.Status.Certificates = nil
+		return switchState(sFnUpdateTriggerAuthentications)
+	}
+
+	u, err := r.kedaMetricsServerDeployment()
+	if err != nil {
+		return stopWithCertificatesErr(s, err)
+	}
+	if err := updateObj(u, certificatesSecretName, mountCertificates); err != nil {
+		return stopWithCertificatesErr(s, err)
+	}
+
+	useCertManager := false
+	if cfg.CertManager {
+		useCertManager, err = crdsInstalled(ctx, r.Client, certManagerCRDs)
+		if err != nil {
+			return stopWithCertificatesErr(s, err)
+		}
+	}
+
+	if useCertManager {
+		err = certManagerCertificates(ctx, r, s)
+	} else {
+		err = pruneCertManagerObjs(ctx, r, s)
+		if err == nil {
+			err = selfManagedCertificates(ctx, r, s)
+		}
+	}
+	if err != nil {
+		return stopWithCertificatesErr(s, err)
+	}
+	return switchState(sFnUpdateTriggerAuthentications)
+}
+
+func stopWithCertificatesErr(s *systemState, err error) (stateFn, *ctrl.Result, error) {
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonCertificatesErr,
+		err,
+	)
+	return stopWithErrorAnNoRequeue(err)
+}
@@ -0,0 +1,195 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/crypto/certs"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func testCertificatesFsm(t *testing.T, objs ...client.Object) *fsm {
+	r := testMonitoringFsm(t, objs...)
+	r.Objs = testManifestFsm(t).Objs
+	return r
+}
+
+func testCertificatesState(certManager bool) *systemState {
+	return &systemState{
+		namespace: "kyma-system",
+		instance: v1alpha1.Keda{
+			Spec: v1alpha1.KedaSpec{
+				Certificates: &v1alpha1.CertificatesCfg{
+					Enabled:     true,
+					CertManager: certManager,
+				},
+			},
+		},
+	}
+}
+
+func Test_sFnUpdateCertificates(t *testing.T) {
+	t.Run("self-managed", func(t *testing.T) {
+		r := testCertificatesFsm(t)
+		s := testCertificatesState(false)
+
+		fn, _, err := sFnUpdateCertificates(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateTriggerAuthentications), fnName(fn))
+
+		// the secret is applied with keda components
+		secret := r.Objs[len(r.Objs)-1]
+		require.Equal(t, certificatesSecretName, secret.GetName())
+		caCert, _, err := unstructured.NestedString(secret.Object, "data", caCertKey)
+		require.NoError(t, err)
+
+		// the apiservice trusts the generated ca
+		apiService, err := r.firstUnstructed(isMetricsAPIService)
+		require.NoError(t, err)
+		caBundle, _, err := unstructured.NestedString(apiService.Object, "spec", "caBundle")
+		require.NoError(t, err)
+		require.Equal(t, caCert, caBundle)
+		_, found, err := unstructured.NestedBool(apiService.Object, "spec", "insecureSkipTLSVerify")
+		require.NoError(t, err)
+		require.False(t, found)
+
+		// the metrics server serves the generated certificate
+		u, err := r.kedaMetricsServerDeployment()
+		require.NoError(t, err)
+		var deployment appsv1.Deployment
+		require.NoError(t, fromUnstructured(u.Object, &deployment))
+		require.Contains(t, deployment.Spec.Template.Spec.Containers[0].Args, "--tls-cert-file=/certs/tls.crt")
+
+		require.Equal(t, IssuerKedaManager, s.instance.Status.Certificates.Issuer)
+		require.NotNil(t, s.instance.Status.Certificates.NotAfter)
+		require.Greater(t, s.requeueAfter, time.Duration(0))
+	})
+
+	t.Run("self-managed in dry run", func(t *testing.T) {
+		r := testCertificatesFsm(t)
+		r.DryRun = true
+		s := testCertificatesState(false)
+
+		fn, _, err := sFnUpdateCertificates(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateTriggerAuthentications), fnName(fn))
+
+		// certificates are neither generated nor reported
+		_, err = r.firstUnstructed(func(u unstructured.Unstructured) bool {
+			return u.GetKind() == "Secret" && u.GetName() == certificatesSecretName
+		})
+		require.ErrorIs(t, err, ErrNotFound)
+		require.Nil(t, s.instance.Status.Certificates)
+		require.Zero(t, s.requeueAfter)
+	})
+
+	t.Run("existing certificates are reused", func(t *testing.T) {
+		bundle, err := certs.Ensure(certs.Bundle{}, metricsServerDNSNames("kyma-system"), time.Now())
+		require.NoError(t, err)
+		secret := corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kyma-system", Name: certificatesSecretName},
+			Data: map[string][]byte{
+				caCertKey:               bundle.CACert,
+				caKeyKey:                bundle.CAKey,
+				corev1.TLSCertKey:       bundle.Cert,
+				corev1.TLSPrivateKeyKey: bundle.Key,
+			},
+		}
+
+		r := testCertificatesFsm(t, &secret)
+		s := testCertificatesState(false)
+
+		_, _, err = sFnUpdateCertificates(context.Background(), r, s)
+		require.NoError(t, err)
+
+		apiService, err := r.firstUnstructed(isMetricsAPIService)
+		require.NoError(t, err)
+		caBundle, _, err := unstructured.NestedString(apiService.Object, "spec", "caBundle")
+		require.NoError(t, err)
+		require.Equal(t, base64.StdEncoding.EncodeToString(bundle.CACert), caBundle)
+	})
+
+	t.Run("cert-manager", func(t *testing.T) {
+		var crds []client.Object
+		for _, name := range certManagerCRDs {
+			crds = append(crds, &apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+			})
+		}
+		r := testCertificatesFsm(t, crds...)
+		s := testCertificatesState(true)
+
+		fn, _, err := sFnUpdateCertificates(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateTriggerAuthentications), fnName(fn))
+
+		apiService, err := r.firstUnstructed(isMetricsAPIService)
+		require.NoError(t, err)
+		require.Equal(t, "kyma-system/"+matricsServerName, apiService.GetAnnotations()[certManagerInjectCAFrom])
+		require.Equal(t, IssuerCertManager, s.instance.Status.Certificates.Issuer)
+	})
+
+	t.Run("cert-manager not installed", func(t *testing.T) {
+		caSecret := corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kyma-system", Name: certManagerCAName},
+		}
+		r := testCertificatesFsm(t, &caSecret)
+		s := testCertificatesState(true)
+		s.instance.Status.Certificates = &v1alpha1.CertificatesStatus{Issuer: IssuerCertManager}
+
+		_, _, err := sFnUpdateCertificates(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, IssuerKedaManager, s.instance.Status.Certificates.Issuer)
+
+		// objects of cert-manager are pruned
+		err = r.Get(context.Background(), types.NamespacedName{Namespace: "kyma-system", Name: certManagerCAName}, &caSecret)
+		require.Error(t, err)
+		require.NoError(t, client.IgnoreNotFound(err))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		secret := corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kyma-system", Name: certificatesSecretName},
+		}
+		caSecret := corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kyma-system", Name: certManagerCAName},
+		}
+		r := testCertificatesFsm(t, &secret, &caSecret)
+		s := &systemState{namespace: "kyma-system"}
+		s.instance.Status.Certificates = &v1alpha1.CertificatesStatus{Issuer: IssuerCertManager}
+
+		fn, _, err := sFnUpdateCertificates(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateTriggerAuthentications), fnName(fn))
+		require.Nil(t, s.instance.Status.Certificates)
+
+		// the secrets are pruned, including the ca issued by cert-manager
+		for _, name := range []string{certificatesSecretName, certManagerCAName} {
+			err = r.Get(context.Background(), types.NamespacedName{Namespace: "kyma-system", Name: name}, &secret)
+			require.Error(t, err)
+			require.NoError(t, client.IgnoreNotFound(err))
+		}
+	})
+
+	t.Run("disabled without created certificates", func(t *testing.T) {
+		r := testCertificatesFsm(t)
+		c := &deleteCountingClient{Client: r.Client}
+		r.Client = c
+		s := &systemState{namespace: "kyma-system"}
+
+		fn, _, err := sFnUpdateCertificates(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateTriggerAuthentications), fnName(fn))
+		require.Zero(t, c.deletes)
+	})
+}
@@ -5,7 +5,9 @@ import (
 
 	"github.com/go-errors/errors"
 	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"go.opentelemetry.io/otel/trace"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -20,6 +22,15 @@ var (
 )
 
 func sFnDeleteResources(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	if err := deleteAllGeneratedObjs(ctx, r, s); err != nil {
+		s.instance.UpdateStateFromErr(
+			v1alpha1.ConditionTypeInstalled,
+			v1alpha1.ConditionReasonDeletionErr,
+			DeletionErr,
+		)
+		return stopWithErrorAnNoRequeue(err)
+	}
+
 	if safeDeleteStrategy {
 		return switchState(sFnSafeDeleteStrategy)
 	}
@@ -49,6 +60,7 @@ func alwaysTrueFilter(unstructured.Unstructured) bool {
 type filterFunc func(unstructured.Unstructured) bool
 
 func deleteResourcesWithFilter(ctx context.Context, r *fsm, s *systemState, filterFunc filterFunc) (stateFn, *ctrl.Result, error) {
+	span := trace.SpanFromContext(ctx)
 	var err error
 	for _, obj := range r.Objs {
 		if !filterFunc(obj) {
@@ -63,12 +75,18 @@ func deleteResourcesWithFilter(ctx context.Context, r *fsm, s *systemState, filt
 			With("objName", obj.GetName()).
 			With("gvk", obj.GroupVersionKind()).
 			Debug("deleting")
+		span.AddEvent("delete", objAttributes(obj))
 
 		err = r.Delete(ctx, &obj)
+		if err == nil {
+			s.events.record(EventReasonDeleted, obj)
+		}
 		err = client.IgnoreNotFound(err)
 
 		if err != nil {
 			r.log.With("deleting resource").Error(err)
+			span.RecordError(err, objAttributes(obj))
+			s.events.failed(EventReasonDeleteFailed, obj, err)
 		}
 	}
 
@@ -83,3 +101,42 @@ func deleteResourcesWithFilter(ctx context.Context, r *fsm, s *systemState, filt
 	}
 	return switchState(sFnRemoveFinalizer)
 }
+
+// generatedObjs returns all objects which can be generated from the instance spec
+func generatedObjs(namespace string) ([]unstructured.Unstructured, error) {
+	networkPolicies, err := networkPolicyObjs(namespace, &v1alpha1.NetworkPolicyCfg{})
+	if err != nil {
+		return nil, err
+	}
+
+	generated := append(monitoringObjs(namespace), networkPolicies...)
+	return append(generated, certificatesObjs(namespace)...), nil
+}
+
+// deleteAllGeneratedObjs removes all objects generated from the instance spec
+func deleteAllGeneratedObjs(ctx context.Context, r *fsm, s *systemState) error {
+	generated, err := generatedObjs(s.namespace)
+	if err != nil {
+		return err
+	}
+	if err := deleteGeneratedObjs(ctx, r, s, generated, EventReasonDeleted); err != nil {
+		return err
+	}
+	return pruneTriggerAuthentications(ctx, r, s, nil, EventReasonDeleted)
+}
+
+// deleteGeneratedObjs removes objects generated from the instance spec, if
+// there are any; removed objects are recorded with given reason
+func deleteGeneratedObjs(ctx context.Context, r *fsm, s *systemState, objs []unstructured.Unstructured, reason string) error {
+	for _, obj := range objs {
+		err := r.Delete(ctx, &obj)
+		if err == nil {
+			s.events.record(reason, obj)
+		}
+		if client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+			s.events.failed(EventReasonDeleteFailed, obj, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -2,12 +2,15 @@ package reconciler
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"runtime"
 	"testing"
 
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -111,3 +114,35 @@ func canGetFakeResource(c client.Client, u unstructured.Unstructured) error {
 		},
 		&u)
 }
+
+// nameRequiredClient fails deletions of objects without a name, as the
+// api server does
+type nameRequiredClient struct {
+	client.Client
+}
+
+func (c *nameRequiredClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if obj.GetName() == "" {
+		return errors.New("resource name may not be empty")
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func Test_sFnDeleteResources_generatedObjs(t *testing.T) {
+	managed := triggerAuthenticationObjs([]v1alpha1.TriggerAuthentication{{Name: "managed"}})[0]
+	c := fake.NewClientBuilder().WithObjects(&managed).Build()
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		K8s: K8s{Client: &nameRequiredClient{Client: c}},
+	}
+	s := &systemState{namespace: "kyma-system"}
+
+	fn, resp, err := sFnDeleteResources(context.Background(), r, s)
+	require.Nil(t, resp)
+	require.NoError(t, err)
+	require.Equal(t, fnName(sFnSafeDeleteStrategy), fnName(fn))
+
+	// managed trigger authentications are removed by name
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(&managed), &managed)
+	require.True(t, apierrors.IsNotFound(err))
+}
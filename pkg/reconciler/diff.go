@@ -0,0 +1,108 @@
+package reconciler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// fields set by the api server which are not part of the object's desired state
+var ignoredDiffFields = [][]string{
+	{"status"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "uid"},
+}
+
+func withoutIgnoredFields(obj map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		result[k] = v
+	}
+
+	for _, path := range ignoredDiffFields {
+		if len(path) == 1 {
+			delete(result, path[0])
+			continue
+		}
+
+		nested, ok := result[path[0]].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		copied := make(map[string]interface{}, len(nested))
+		for k, v := range nested {
+			copied[k] = v
+		}
+		delete(copied, path[1])
+		result[path[0]] = copied
+	}
+	return result
+}
+
+// diffObjs returns human readable changes between the live and the desired object
+func diffObjs(live, desired map[string]interface{}) []string {
+	var result []string
+	diffValues("", withoutIgnoredFields(live), withoutIgnoredFields(desired), &result)
+	return result
+}
+
+func diffValues(path string, live, desired interface{}, result *[]string) {
+	liveMap, liveIsMap := live.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	if liveIsMap && desiredIsMap {
+		for _, key := range sortedKeys(liveMap, desiredMap) {
+			liveValue, inLive := liveMap[key]
+			desiredValue, inDesired := desiredMap[key]
+			keyPath := joinPath(path, key)
+
+			switch {
+			case !inLive:
+				*result = append(*result, fmt.Sprintf("+ %s: %v", keyPath, desiredValue))
+			case !inDesired:
+				*result = append(*result, fmt.Sprintf("- %s: %v", keyPath, liveValue))
+			default:
+				diffValues(keyPath, liveValue, desiredValue, result)
+			}
+		}
+		return
+	}
+
+	liveSlice, liveIsSlice := live.([]interface{})
+	desiredSlice, desiredIsSlice := desired.([]interface{})
+	if liveIsSlice && desiredIsSlice && len(liveSlice) == len(desiredSlice) {
+		for i := range liveSlice {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), liveSlice[i], desiredSlice[i], result)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(live, desired) {
+		*result = append(*result, fmt.Sprintf("~ %s: %v -> %v", path, live, desired))
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(maps ...map[string]interface{}) []string {
+	visited := map[string]struct{}{}
+	var result []string
+	for _, m := range maps {
+		for key := range m {
+			if _, found := visited[key]; found {
+				continue
+			}
+			visited[key] = struct{}{}
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,118 @@
+package reconciler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_diffObjs(t *testing.T) {
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "test",
+			"resourceVersion": "123",
+			"uid":             "abc",
+			"labels": map[string]interface{}{
+				"removed": "true",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"args":     []interface{}{"--zap-log-level=info"},
+		},
+		"status": map[string]interface{}{
+			"readyReplicas": int64(1),
+		},
+	}
+	desired := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "test",
+			"resourceVersion": "124",
+			"annotations": map[string]interface{}{
+				"added": "true",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"args":     []interface{}{"--zap-log-level=debug"},
+		},
+	}
+
+	require.Equal(t, []string{
+		"+ metadata.annotations: map[added:true]",
+		"- metadata.labels: map[removed:true]",
+		"~ spec.args[0]: --zap-log-level=info -> --zap-log-level=debug",
+		"~ spec.replicas: 1 -> 2",
+	}, diffObjs(live, desired))
+
+	require.Empty(t, diffObjs(live, live))
+	// ignored fields of the live object are not modified
+	require.Equal(t, "123", live["metadata"].(map[string]interface{})["resourceVersion"])
+}
+
+func Test_dryRunKey(t *testing.T) {
+	var u unstructured.Unstructured
+	u.SetKind("ClusterRole")
+	u.SetName("system:keda-manager")
+	require.Equal(t, "ClusterRole..system-keda-manager", dryRunKey(u))
+
+	u.SetKind("Deployment")
+	u.SetNamespace("kyma-system")
+	u.SetName(operatorName)
+	require.Equal(t, "Deployment.kyma-system.keda-manager", dryRunKey(u))
+}
+
+func Test_truncateDiff(t *testing.T) {
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("~ %d", i))
+	}
+	diff := strings.Join(lines, "\n")
+
+	require.Equal(t, diff, truncateDiff(diff, len(diff)))
+
+	truncated := truncateDiff(diff, 30)
+	require.Equal(t, "~ 0\n~ 1\n... and 8 more lines", truncated)
+	require.LessOrEqual(t, len(truncated), 30)
+}
+
+func Test_dryRunData(t *testing.T) {
+	t.Run("diffs of single objects are bounded", func(t *testing.T) {
+		result := dryRunResult{
+			changed: 2,
+			diffs: map[string]string{
+				"Deployment.kyma-system.a": strings.Repeat("~ spec.replicas\n", maxDryRunDiffLen),
+				"Deployment.kyma-system.b": "~ spec.replicas",
+			},
+		}
+
+		data := dryRunData(&result)
+		require.Equal(t, result.String(), data[dryRunSummaryKey])
+		require.LessOrEqual(t, len(data["Deployment.kyma-system.a"]), maxDryRunDiffLen)
+		require.Contains(t, data["Deployment.kyma-system.a"], "more lines")
+		require.Equal(t, "~ spec.replicas", data["Deployment.kyma-system.b"])
+		require.NotContains(t, data, dryRunTruncatedKey)
+	})
+
+	t.Run("all diffs are bounded", func(t *testing.T) {
+		result := dryRunResult{diffs: map[string]string{}}
+		for i := 0; i < 2*maxDryRunDataLen/maxDryRunDiffLen; i++ {
+			result.diffs[fmt.Sprintf("ConfigMap.kyma-system.%02d", i)] = strings.Repeat("x", maxDryRunDiffLen)
+		}
+
+		data := dryRunData(&result)
+		size := 0
+		for key, value := range data {
+			if key != dryRunTruncatedKey {
+				size += len(key) + len(value)
+			}
+		}
+		require.LessOrEqual(t, size, maxDryRunDataLen)
+		require.Contains(t, data, "ConfigMap.kyma-system.00")
+		require.NotContains(t, data, "ConfigMap.kyma-system.15")
+		require.Contains(t, data[dryRunTruncatedKey], "ConfigMap.kyma-system.15")
+	})
+}
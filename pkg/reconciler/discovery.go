@@ -0,0 +1,85 @@
+package reconciler
+
+import (
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+)
+
+// cachedDiscovery caches the server version in addition to the API groups
+// and resources cached by the memory discovery client; the cache expires
+// after its ttl, so cluster upgrades are discovered again
+type cachedDiscovery struct {
+	discovery.CachedDiscoveryInterface
+
+	mu        sync.Mutex
+	ttl       time.Duration
+	refreshed time.Time
+	version   *version.Info
+}
+
+// NewCachedDiscovery returns the discovery client used by preflight checks,
+// so the cluster is not discovered again on every reconciliation
+func NewCachedDiscovery(d discovery.DiscoveryInterface, ttl time.Duration) discovery.CachedDiscoveryInterface {
+	return &cachedDiscovery{
+		CachedDiscoveryInterface: memory.NewMemCacheClient(d),
+		ttl:                      ttl,
+		refreshed:                time.Now(),
+	}
+}
+
+// expire invalidates the cache once its ttl passed; the caller must hold the lock
+func (d *cachedDiscovery) expire() {
+	if time.Since(d.refreshed) < d.ttl {
+		return
+	}
+	d.version = nil
+	d.CachedDiscoveryInterface.Invalidate()
+	d.refreshed = time.Now()
+}
+
+func (d *cachedDiscovery) ServerVersion() (*version.Info, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.expire()
+
+	if d.version != nil {
+		return d.version, nil
+	}
+
+	info, err := d.CachedDiscoveryInterface.ServerVersion()
+	if err != nil {
+		return nil, err
+	}
+	d.version = info
+	return info, nil
+}
+
+func (d *cachedDiscovery) ServerGroups() (*metav1.APIGroupList, error) {
+	d.mu.Lock()
+	d.expire()
+	d.mu.Unlock()
+
+	return d.CachedDiscoveryInterface.ServerGroups()
+}
+
+func (d *cachedDiscovery) Invalidate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.version = nil
+	d.CachedDiscoveryInterface.Invalidate()
+	d.refreshed = time.Now()
+}
+
+// invalidateDiscovery makes sure the cluster is discovered again on the next
+// reconciliation, e.g. after failed checks were fixed
+func invalidateDiscovery(d discovery.DiscoveryInterface) {
+	if cached, ok := d.(discovery.CachedDiscoveryInterface); ok {
+		cached.Invalidate()
+	}
+}
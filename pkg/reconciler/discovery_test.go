@@ -0,0 +1,59 @@
+package reconciler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func Test_cachedDiscovery(t *testing.T) {
+	fake := &fakediscovery.FakeDiscovery{
+		Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+			{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}}},
+		}},
+		FakedServerVersion: &version.Info{GitVersion: "v1.25.0"},
+	}
+	countRequests := func() map[string]int {
+		result := map[string]int{}
+		for _, action := range fake.Actions() {
+			result[action.GetResource().Resource]++
+		}
+		return result
+	}
+
+	d := NewCachedDiscovery(fake, time.Hour)
+	for i := 0; i < 3; i++ {
+		info, err := d.ServerVersion()
+		require.NoError(t, err)
+		require.Equal(t, "v1.25.0", info.GitVersion)
+
+		groups, err := d.ServerGroups()
+		require.NoError(t, err)
+		require.Len(t, groups.Groups, 1)
+	}
+	require.Equal(t, map[string]int{"version": 1, "group": 1, "resource": 1}, countRequests())
+
+	// the cluster is discovered again after the cache is invalidated
+	invalidateDiscovery(d)
+	_, err := d.ServerVersion()
+	require.NoError(t, err)
+	_, err = d.ServerGroups()
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"version": 2, "group": 2, "resource": 2}, countRequests())
+
+	t.Run("expired cache", func(t *testing.T) {
+		d := NewCachedDiscovery(fake, 0)
+		before := countRequests()["version"]
+
+		_, err := d.ServerVersion()
+		require.NoError(t, err)
+		_, err = d.ServerVersion()
+		require.NoError(t, err)
+		require.Equal(t, before+2, countRequests()["version"])
+	})
+}
@@ -0,0 +1,307 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/metrics"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// keeps the condition message readable
+	maxDriftMessageLen = 1024
+)
+
+// driftedField is a field of the desired object changed by other actors
+type driftedField struct {
+	obj      unstructured.Unstructured
+	path     string
+	managers []string
+}
+
+func (f driftedField) String() string {
+	return fmt.Sprintf("%s %s %s changed by %s",
+		f.obj.GetKind(),
+		client.ObjectKeyFromObject(&f.obj),
+		f.path,
+		strings.Join(f.managers, ","),
+	)
+}
+
+// fieldsOwners returns fields of all managers of given object, but the keda-manager
+func fieldsOwners(live unstructured.Unstructured) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, entry := range live.GetManagedFields() {
+		if entry.Manager == fieldManager || entry.FieldsV1 == nil {
+			continue
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, err
+		}
+
+		// the manager may own fields with many operations and subresources
+		if owned, found := result[entry.Manager].(map[string]interface{}); found {
+			fields = mergeFields(owned, fields)
+		}
+		result[entry.Manager] = fields
+	}
+	return result, nil
+}
+
+func mergeFields(dst, src map[string]interface{}) map[string]interface{} {
+	for key, value := range src {
+		dstValue, dstIsMap := dst[key].(map[string]interface{})
+		srcValue, srcIsMap := value.(map[string]interface{})
+		if dstIsMap && srcIsMap {
+			dst[key] = mergeFields(dstValue, srcValue)
+			continue
+		}
+		dst[key] = value
+	}
+	return dst
+}
+
+// detectDrift returns fields of the desired object which differ on the
+// live object and are owned by other managers; fields defaulted by the
+// api server are not part of the desired object, so they are ignored
+func detectDrift(desired, live unstructured.Unstructured) ([]driftedField, error) {
+	owners, err := fieldsOwners(live)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []driftedField
+	report := func(path string, managers []string) {
+		sort.Strings(managers)
+		result = append(result, driftedField{
+			obj:      desired,
+			path:     path,
+			managers: managers,
+		})
+	}
+
+	desiredObj := withoutIgnoredFields(desired.Object)
+	liveObj := withoutIgnoredFields(live.Object)
+	driftValues("", desiredObj, liveObj, owners, report)
+	return result, nil
+}
+
+func driftValues(path string, desired, live interface{}, owners map[string]interface{}, report func(string, []string)) {
+	// managers owning the whole value, e.g. atomic lists
+	var atomicOwners []string
+	nested := map[string]interface{}{}
+	for manager, fields := range owners {
+		fieldsMap, ok := fields.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if len(fieldsMap) == 0 || len(fieldsMap) == 1 && fieldsMap["."] != nil {
+			atomicOwners = append(atomicOwners, manager)
+			continue
+		}
+		nested[manager] = fieldsMap
+	}
+
+	if len(atomicOwners) != 0 && !valuesMatch(desired, live) {
+		report(path, atomicOwners)
+		return
+	}
+
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveValue, _ := live.(map[string]interface{})
+		for _, key := range sortedKeys(desiredValue) {
+			driftValues(
+				joinPath(path, key),
+				desiredValue[key],
+				liveValue[key],
+				childFields(nested, func(fields map[string]interface{}) interface{} {
+					return fields["f:"+key]
+				}),
+				report,
+			)
+		}
+	case []interface{}:
+		liveValue, _ := live.([]interface{})
+		for i := range desiredValue {
+			var liveItem interface{}
+			if i < len(liveValue) {
+				liveItem = liveValue[i]
+			}
+			driftValues(
+				fmt.Sprintf("%s[%d]", path, i),
+				desiredValue[i],
+				liveItem,
+				childFields(nested, func(fields map[string]interface{}) interface{} {
+					return listItemFields(fields, i, desiredValue[i])
+				}),
+				report,
+			)
+		}
+	}
+}
+
+func childFields(owners map[string]interface{}, child func(map[string]interface{}) interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for manager, fields := range owners {
+		value := child(fields.(map[string]interface{}))
+		if value == nil {
+			continue
+		}
+		result[manager] = value
+	}
+	return result
+}
+
+// listItemFields returns fields of the list item in one of the formats
+// of the managed fields: by keys of the item, by its value or by its index
+func listItemFields(fields map[string]interface{}, index int, item interface{}) interface{} {
+	if value, found := fields[fmt.Sprintf("i:%d", index)]; found {
+		return value
+	}
+
+	itemMap, isMap := item.(map[string]interface{})
+	if !isMap {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil
+		}
+		return fields["v:"+string(data)]
+	}
+
+	for key, value := range fields {
+		if !strings.HasPrefix(key, "k:") {
+			continue
+		}
+		var keys map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(key, "k:")), &keys); err != nil {
+			continue
+		}
+		if valuesMatch(keys, itemMap) {
+			return value
+		}
+	}
+	return nil
+}
+
+// valuesMatch checks if all fields of the desired value are equal on the live value
+func valuesMatch(desired, live interface{}) bool {
+	switch desiredValue := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		liveValue, _ := live.(map[string]interface{})
+		for key := range desiredValue {
+			if !valuesMatch(desiredValue[key], liveValue[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		liveValue, _ := live.([]interface{})
+		if len(desiredValue) != len(liveValue) {
+			return false
+		}
+		for i := range desiredValue {
+			if !valuesMatch(desiredValue[i], liveValue[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if fmt.Sprint(desired) == fmt.Sprint(live) {
+		return true
+	}
+	// quantities are normalized by the api server, e.g. 0.5 is 500m
+	desiredStr, desiredIsStr := desired.(string)
+	liveStr, liveIsStr := live.(string)
+	if !desiredIsStr || !liveIsStr {
+		return false
+	}
+	desiredQuantity, err := resource.ParseQuantity(desiredStr)
+	if err != nil {
+		return false
+	}
+	liveQuantity, err := resource.ParseQuantity(liveStr)
+	return err == nil && desiredQuantity.Cmp(liveQuantity) == 0
+}
+
+func driftMessage(fields []driftedField) string {
+	var msg strings.Builder
+	for i, field := range fields {
+		line := field.String()
+		if i > 0 {
+			line = "; " + line
+		}
+		if msg.Len()+len(line) > maxDriftMessageLen {
+			fmt.Fprintf(&msg, "; and %d more", len(fields)-i)
+			break
+		}
+		msg.WriteString(line)
+	}
+	return msg.String()
+}
+
+// sFnDetectDrift reports fields of keda components changed by other actors
+// before they are reverted by the apply
+func sFnDetectDrift(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	var drifted []driftedField
+	for _, obj := range r.Objs {
+		var live unstructured.Unstructured
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		if err := r.Get(ctx, client.ObjectKeyFromObject(&obj), &live); err != nil {
+			// missing objects are created by the apply
+			if client.IgnoreNotFound(err) != nil {
+				r.log.With("err", err).With("name", obj.GetName()).Warn("unable to detect drift")
+				continue
+			}
+			s.events.setLiveVersion(obj, "")
+			continue
+		}
+		s.events.setLiveVersion(obj, live.GetResourceVersion())
+
+		fields, err := detectDrift(obj, live)
+		if err != nil {
+			r.log.With("err", err).With("name", obj.GetName()).Warn("unable to detect drift")
+			continue
+		}
+		if len(fields) == 0 {
+			s.setLiveObj(obj, live)
+		}
+		drifted = append(drifted, fields...)
+	}
+
+	if len(drifted) == 0 {
+		meta.RemoveStatusCondition(&s.instance.Status.Conditions, string(v1alpha1.ConditionTypeDriftDetected))
+		return switchState(sFnApply)
+	}
+
+	for _, field := range drifted {
+		for _, manager := range field.managers {
+			metrics.DriftedFields.WithLabelValues(field.obj.GetKind(), manager).Inc()
+		}
+	}
+
+	msg := driftMessage(drifted)
+	r.log.With("drift", msg).Info("drift detected")
+	s.instance.UpdateCondition(
+		v1alpha1.ConditionTypeDriftDetected,
+		metav1.ConditionTrue,
+		v1alpha1.ConditionReasonDriftDetected,
+		msg,
+	)
+	return switchState(sFnApply)
+}
@@ -0,0 +1,137 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testDriftDeployment(image, cpu string) unstructured.Unstructured {
+	return unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      operatorName,
+				"namespace": "kyma-system",
+			},
+			"spec": map[string]interface{}{
+				"replicas": int64(1),
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":  operatorName,
+								"image": image,
+								"args":  []interface{}{"--zap-log-level=info"},
+								"resources": map[string]interface{}{
+									"limits": map[string]interface{}{
+										"cpu": cpu,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func testManagedFields(manager, fields string) metav1.ManagedFieldsEntry {
+	return metav1.ManagedFieldsEntry{
+		Manager:    manager,
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(fields)},
+	}
+}
+
+func Test_detectDrift(t *testing.T) {
+	desired := testDriftDeployment("keda:2.8.0", "0.5")
+
+	t.Run("no drift", func(t *testing.T) {
+		live := testDriftDeployment("keda:2.8.0", "500m")
+		live.SetManagedFields([]metav1.ManagedFieldsEntry{
+			testManagedFields(fieldManager, `{"f:spec":{"f:replicas":{}}}`),
+		})
+
+		fields, err := detectDrift(desired, live)
+		require.NoError(t, err)
+		require.Empty(t, fields)
+	})
+
+	t.Run("fields changed by others", func(t *testing.T) {
+		live := testDriftDeployment("keda:hotfix", "1")
+		live.SetManagedFields([]metav1.ManagedFieldsEntry{
+			testManagedFields(fieldManager, `{"f:spec":{"f:replicas":{}}}`),
+			testManagedFields("kubectl-edit", `{"f:spec":{"f:template":{"f:spec":{"f:containers":{
+				"k:{\"name\":\"keda-manager\"}":{".":{},"f:image":{},"f:resources":{"f:limits":{"f:cpu":{}}}}}}}}}`),
+		})
+
+		fields, err := detectDrift(desired, live)
+		require.NoError(t, err)
+		require.Len(t, fields, 2)
+		require.Equal(t,
+			"Deployment kyma-system/keda-manager spec.template.spec.containers[0].image changed by kubectl-edit",
+			fields[0].String(),
+		)
+		require.Equal(t, "spec.template.spec.containers[0].resources.limits.cpu", fields[1].path)
+	})
+
+	t.Run("fields changed by keda-manager", func(t *testing.T) {
+		live := testDriftDeployment("keda:2.7.0", "0.5")
+		live.SetManagedFields([]metav1.ManagedFieldsEntry{
+			testManagedFields(fieldManager, `{"f:spec":{"f:template":{"f:spec":{"f:containers":{
+				"k:{\"name\":\"keda-manager\"}":{".":{},"f:image":{}}}}}}}`),
+		})
+
+		fields, err := detectDrift(desired, live)
+		require.NoError(t, err)
+		require.Empty(t, fields)
+	})
+}
+
+func Test_sFnDetectDrift(t *testing.T) {
+	live := testDriftDeployment("keda:hotfix", "0.5")
+	live.SetManagedFields([]metav1.ManagedFieldsEntry{
+		testManagedFields("kubectl-edit", `{"f:spec":{"f:replicas":{},"f:template":{"f:spec":{"f:containers":{
+			"k:{\"name\":\"keda-manager\"}":{"f:image":{}}}}}}}`),
+	})
+	recorder := record.NewFakeRecorder(1)
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{
+			Objs: []unstructured.Unstructured{testDriftDeployment("keda:2.8.0", "0.5")},
+		},
+		K8s: K8s{
+			Client:        fake.NewClientBuilder().WithObjects(&live).Build(),
+			EventRecorder: recorder,
+		},
+	}
+	s := &systemState{}
+
+	fn, _, err := sFnDetectDrift(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, fnName(sFnApply), fnName(fn))
+	// the condition change is recorded as an event once the status is updated
+	require.Empty(t, recorder.Events)
+
+	condition := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypeDriftDetected))
+	require.NotNil(t, condition)
+	require.Contains(t, condition.Message, "spec.template.spec.containers[0].image changed by kubectl-edit")
+
+	// drift is corrected
+	r.Client = fake.NewClientBuilder().Build()
+	_, _, err = sFnDetectDrift(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Nil(t, meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypeDriftDetected)))
+}
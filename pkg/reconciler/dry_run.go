@@ -0,0 +1,225 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	dryRunSummaryKey   = "summary"
+	dryRunTruncatedKey = "truncated"
+	// keep the dry run result well below the size limit of a ConfigMap
+	maxDryRunDiffLen = 64 * 1024
+	maxDryRunDataLen = 512 * 1024
+)
+
+var (
+	invalidConfigMapKeyChars = regexp.MustCompile(`[^-._a-zA-Z0-9]`)
+)
+
+func (m *fsm) isDryRun(k *v1alpha1.Keda) bool {
+	return m.DryRun || k.IsDryRun()
+}
+
+func dryRunConfigMapName(k *v1alpha1.Keda) string {
+	return fmt.Sprintf("%s-dry-run", k.Name)
+}
+
+type dryRunResult struct {
+	created   int
+	changed   int
+	unchanged int
+	failed    int
+	diffs     map[string]string
+}
+
+func (r *dryRunResult) String() string {
+	return fmt.Sprintf("%d to create, %d to change, %d unchanged, %d failed",
+		r.created, r.changed, r.unchanged, r.failed)
+}
+
+func dryRunKey(u unstructured.Unstructured) string {
+	key := strings.Join([]string{u.GetKind(), u.GetNamespace(), u.GetName()}, ".")
+	return invalidConfigMapKeyChars.ReplaceAllString(key, "-")
+}
+
+// sFnDryRunApply applies objects with the server side dry run and stores
+// the changes the apply would cause, instead of mutating the cluster
+func sFnDryRunApply(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	result := dryRunResult{diffs: map[string]string{}}
+	for _, obj := range r.Objs {
+		key := dryRunKey(obj)
+
+		var live unstructured.Unstructured
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		getErr := r.Get(ctx, client.ObjectKeyFromObject(&obj), &live)
+		if client.IgnoreNotFound(getErr) != nil {
+			result.failed++
+			result.diffs[key] = getErr.Error()
+			continue
+		}
+
+		desired := obj.DeepCopy()
+		// the apply stamps objects with their checksum, it is no change itself
+		if err := setAppliedHash(desired); err != nil {
+			r.log.With("err", err).With("name", obj.GetName()).Warn("unable to calculate applied hash")
+		}
+		err := r.Patch(ctx, desired, client.Apply, &client.PatchOptions{
+			Force:        pointer.Bool(true),
+			FieldManager: fieldManager,
+			DryRun:       []string{metav1.DryRunAll},
+		})
+
+		switch {
+		// objects in a namespace that does not exist yet can not be applied
+		case apierrors.IsNotFound(getErr) && apierrors.IsNotFound(err):
+			result.created++
+			result.diffs[key] = "+ created"
+		case err != nil:
+			result.failed++
+			result.diffs[key] = err.Error()
+		case apierrors.IsNotFound(getErr):
+			result.created++
+			result.diffs[key] = "+ created"
+		default:
+			changes := diffObjs(live.Object, desired.Object)
+			if len(changes) == 0 {
+				result.unchanged++
+				continue
+			}
+			result.changed++
+			result.diffs[key] = strings.Join(changes, "\n")
+		}
+	}
+
+	r.log.With("result", result.String()).Info("dry run completed")
+
+	name := dryRunConfigMapName(&s.instance)
+	if err := applyDryRunConfigMap(ctx, r, s, &result); err != nil {
+		s.instance.UpdateCondition(
+			v1alpha1.ConditionTypeDryRun,
+			metav1.ConditionFalse,
+			v1alpha1.ConditionReasonDryRunErr,
+			err.Error(),
+		)
+		return stopWithErrorAnNoRequeue(err)
+	}
+
+	s.instance.UpdateCondition(
+		v1alpha1.ConditionTypeDryRun,
+		metav1.ConditionTrue,
+		v1alpha1.ConditionReasonDryRunCompleted,
+		fmt.Sprintf("%s, see configmap %s/%s", result.String(), s.instance.Namespace, name),
+	)
+	return stopWithNoRequeue()
+}
+
+// truncateDiff cuts given diff at a line boundary, so it fits in maxLen
+// together with the marker of cut lines
+func truncateDiff(diff string, maxLen int) string {
+	if len(diff) <= maxLen {
+		return diff
+	}
+
+	lines := strings.Split(diff, "\n")
+	var result strings.Builder
+	for i, line := range lines {
+		marker := fmt.Sprintf("... and %d more lines", len(lines)-i)
+		if result.Len()+len(line)+1+len(marker) > maxLen {
+			result.WriteString(marker)
+			break
+		}
+		result.WriteString(line)
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// dryRunData returns the content of the dry run ConfigMap; diffs of single
+// objects and all diffs together are bounded, and truncation is reported
+func dryRunData(result *dryRunResult) map[string]string {
+	data := map[string]string{
+		dryRunSummaryKey: result.String(),
+	}
+
+	keys := make([]string, 0, len(result.diffs))
+	for key := range result.diffs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	size := len(dryRunSummaryKey) + len(data[dryRunSummaryKey])
+	var omitted []string
+	for _, key := range keys {
+		diff := truncateDiff(result.diffs[key], maxDryRunDiffLen)
+		if size+len(key)+len(diff) > maxDryRunDataLen {
+			omitted = append(omitted, key)
+			continue
+		}
+		data[key] = diff
+		size += len(key) + len(diff)
+	}
+
+	if len(omitted) != 0 {
+		data[dryRunTruncatedKey] = fmt.Sprintf("diffs of %d objects are omitted, as the dry run result exceeds %d bytes: %s",
+			len(omitted), maxDryRunDataLen, strings.Join(omitted, ", "))
+	}
+	return data
+}
+
+func applyDryRunConfigMap(ctx context.Context, r *fsm, s *systemState, result *dryRunResult) error {
+	data := dryRunData(result)
+
+	cm := corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dryRunConfigMapName(&s.instance),
+			Namespace: s.instance.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(&s.instance, v1alpha1.GroupVersion.WithKind("Keda")),
+			},
+		},
+		Data: data,
+	}
+
+	return r.Patch(ctx, &cm, client.Apply, &client.PatchOptions{
+		Force:        pointer.Bool(true),
+		FieldManager: fieldManager,
+	})
+}
+
+// removeDryRunResult removes results of the previous dry run, if there was one
+func removeDryRunResult(ctx context.Context, r *fsm, s *systemState) error {
+	if meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypeDryRun)) == nil {
+		return nil
+	}
+
+	cm := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dryRunConfigMapName(&s.instance),
+			Namespace: s.instance.Namespace,
+		},
+	}
+	if err := client.IgnoreNotFound(r.Delete(ctx, &cm)); err != nil {
+		return err
+	}
+
+	meta.RemoveStatusCondition(&s.instance.Status.Conditions, string(v1alpha1.ConditionTypeDryRun))
+	return nil
+}
@@ -0,0 +1,186 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/crypto/sha256"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// the checksum annotation rolls out keda pods once values of Secrets
+	// or ConfigMaps referenced by their env change
+	envChecksumAnnotation = "keda-manager.kyma-project.io/env-checksum"
+
+	kindSecret    = "Secret"
+	kindConfigMap = "ConfigMap"
+)
+
+type envReference struct {
+	kind string
+	name string
+	key  string
+}
+
+func (r envReference) String() string {
+	return fmt.Sprintf("%s/%s/%s", r.kind, r.name, r.key)
+}
+
+// envReferences returns Secret and ConfigMap keys given env refers to
+func envReferences(env []corev1.EnvVar) []envReference {
+	var result []envReference
+	for _, e := range env {
+		if e.ValueFrom == nil {
+			continue
+		}
+		if ref := e.ValueFrom.SecretKeyRef; ref != nil {
+			result = append(result, envReference{kind: kindSecret, name: ref.Name, key: ref.Key})
+		}
+		if ref := e.ValueFrom.ConfigMapKeyRef; ref != nil {
+			result = append(result, envReference{kind: kindConfigMap, name: ref.Name, key: ref.Key})
+		}
+	}
+	return result
+}
+
+// IsReferenced returns true if given Secret or ConfigMap is referenced by
+// keda components of the instance, so its changes have to be rolled out
+func (c *Cfg) IsReferenced(k *v1alpha1.Keda, obj client.Object) bool {
+	if obj.GetNamespace() != targetNamespace(k, c.Namespace) {
+		return false
+	}
+
+	var kind string
+	switch o := obj.(type) {
+	case *corev1.Secret:
+		kind = kindSecret
+	case *corev1.ConfigMap:
+		kind = kindConfigMap
+	// watched objects carry their metadata only
+	case *metav1.PartialObjectMetadata:
+		kind = o.Kind
+	}
+	if kind != kindSecret && kind != kindConfigMap {
+		return false
+	}
+
+	refs := envReferences(k.Spec.Env)
+	if env, err := k.MetricsServerEnv(); err == nil {
+		refs = append(refs, envReferences(env)...)
+	}
+	if k.Spec.Network != nil && k.Spec.Network.CABundle != nil {
+		caBundle := k.Spec.Network.CABundle
+		refs = append(refs, envReference{kind: string(caBundle.Kind), name: caBundle.Name})
+	}
+
+	for _, ref := range refs {
+		if ref.kind == kind && ref.name == obj.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
+// referencedData returns values of referenced keys; missing objects and keys
+// are left out, so pods are rolled out once they are created
+func referencedData(ctx context.Context, c client.Reader, namespace string, refs []envReference) (map[string][]byte, error) {
+	secrets := map[string]*corev1.Secret{}
+	configMaps := map[string]*corev1.ConfigMap{}
+
+	data := map[string][]byte{}
+	for _, ref := range refs {
+		key := types.NamespacedName{Namespace: namespace, Name: ref.name}
+		switch ref.kind {
+		case kindSecret:
+			secret, found := secrets[ref.name]
+			if !found {
+				secret = &corev1.Secret{}
+				if err := c.Get(ctx, key, secret); client.IgnoreNotFound(err) != nil {
+					return nil, err
+				}
+				secrets[ref.name] = secret
+			}
+			if value, found := secret.Data[ref.key]; found {
+				data[ref.String()] = value
+			}
+		case kindConfigMap:
+			configMap, found := configMaps[ref.name]
+			if !found {
+				configMap = &corev1.ConfigMap{}
+				if err := c.Get(ctx, key, configMap); client.IgnoreNotFound(err) != nil {
+					return nil, err
+				}
+				configMaps[ref.name] = configMap
+			}
+			if value, found := configMap.Data[ref.key]; found {
+				data[ref.String()] = []byte(value)
+			} else if value, found := configMap.BinaryData[ref.key]; found {
+				data[ref.String()] = value
+			}
+		}
+	}
+	return data, nil
+}
+
+func updateDeploymentEnvChecksum(deployment *appsv1.Deployment, checksum string) error {
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
+	deployment.Spec.Template.Annotations[envChecksumAnnotation] = checksum
+	return nil
+}
+
+// sFnUpdateEnvChecksum stamps keda pods with the checksum of Secrets and
+// ConfigMaps their env refers to
+func sFnUpdateEnvChecksum(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	for _, p := range []predicate{isKedaOperatorDeployment, isKedaMatricsServerDeployment} {
+		u, err := r.firstUnstructed(p)
+		if err != nil {
+			return stopWithEnvChecksumErr(s, err)
+		}
+
+		var deployment appsv1.Deployment
+		if err := fromUnstructured(u.Object, &deployment); err != nil {
+			return stopWithEnvChecksumErr(s, err)
+		}
+
+		var refs []envReference
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			refs = append(refs, envReferences(container.Env)...)
+		}
+		if len(refs) == 0 {
+			continue
+		}
+
+		data, err := referencedData(ctx, r.Client, s.namespace, refs)
+		if err != nil {
+			return stopWithEnvChecksumErr(s, err)
+		}
+
+		checksum, err := sha256.DefaultCalculator.CalculateDataSum(data)
+		if err != nil {
+			return stopWithEnvChecksumErr(s, err)
+		}
+
+		if err := updateObj(u, checksum, updateDeploymentEnvChecksum); err != nil {
+			return stopWithEnvChecksumErr(s, err)
+		}
+	}
+	return switchState(sFnValidatePodSecurity)
+}
+
+func stopWithEnvChecksumErr(s *systemState, err error) (stateFn, *ctrl.Result, error) {
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonDeploymentUpdateErr,
+		err,
+	)
+	return stopWithErrorAnNoRequeue(err)
+}
@@ -0,0 +1,124 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var (
+	testSecretEnv = corev1.EnvVar{
+		Name: "KAFKA_PASSWORD",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "kafka"},
+				Key:                  "password",
+			},
+		},
+	}
+)
+
+func testReferencedSecret(password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "kyma-system"},
+		Data:       map[string][]byte{"password": []byte(password)},
+	}
+}
+
+func Test_Cfg_IsReferenced(t *testing.T) {
+	c := Cfg{Namespace: "kyma-system"}
+	k := &v1alpha1.Keda{
+		Spec: v1alpha1.KedaSpec{
+			Env: v1alpha1.EnvVars{testSecretEnv},
+			Network: &v1alpha1.NetworkCfg{
+				CABundle: &v1alpha1.CABundleRef{Kind: v1alpha1.CABundleKindConfigMap, Name: "corporate-ca"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		obj  client.Object
+		want bool
+	}{
+		{
+			name: "env secret",
+			obj:  testReferencedSecret("test"),
+			want: true,
+		},
+		{
+			name: "ca bundle",
+			obj:  &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "corporate-ca", Namespace: "kyma-system"}},
+			want: true,
+		},
+		{
+			name: "configmap with the name of the secret",
+			obj:  &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "kyma-system"}},
+		},
+		{
+			name: "other namespace",
+			obj:  &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, c.IsReferenced(k, tt.obj))
+		})
+	}
+
+	t.Run("custom target namespace", func(t *testing.T) {
+		k := k.DeepCopy()
+		k.Spec.TargetNamespace = pointerTo("keda")
+
+		metadata := func(kind, namespace string) *metav1.PartialObjectMetadata {
+			return &metav1.PartialObjectMetadata{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: kind},
+				ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: namespace},
+			}
+		}
+		// changes are watched in all namespaces by metadata
+		require.True(t, c.IsReferenced(k, metadata("Secret", "keda")))
+		require.False(t, c.IsReferenced(k, metadata("Secret", "kyma-system")))
+		require.False(t, c.IsReferenced(k, metadata("ConfigMap", "keda")))
+	})
+}
+
+func Test_sFnUpdateEnvChecksum(t *testing.T) {
+	checksum := func(t *testing.T, secret *corev1.Secret) string {
+		r := testManifestFsm(t)
+		r.Client = fake.NewClientBuilder().WithObjects(secret).Build()
+		s := &systemState{namespace: "kyma-system"}
+
+		u, err := r.kedaManagerDeployment()
+		require.NoError(t, err)
+		require.NoError(t, updateObj(u, v1alpha1.EnvVars{testSecretEnv}, updateKedaContanierEnvs))
+
+		fn, _, err := sFnUpdateEnvChecksum(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnValidatePodSecurity), fnName(fn))
+
+		// the metrics server does not refer to the secret
+		metricsServer, err := r.kedaMetricsServerDeployment()
+		require.NoError(t, err)
+		var deployment appsv1.Deployment
+		require.NoError(t, fromUnstructured(metricsServer.Object, &deployment))
+		require.NotContains(t, deployment.Spec.Template.Annotations, envChecksumAnnotation)
+
+		annotations, _, err := unstructured.NestedStringMap(u.Object, "spec", "template", "metadata", "annotations")
+		require.NoError(t, err)
+		require.NotEmpty(t, annotations[envChecksumAnnotation])
+		return annotations[envChecksumAnnotation]
+	}
+
+	require.Equal(t, checksum(t, testReferencedSecret("test")), checksum(t, testReferencedSecret("test")))
+	// pods are rolled out once the secret changes
+	require.NotEqual(t, checksum(t, testReferencedSecret("test")), checksum(t, testReferencedSecret("changed")))
+}
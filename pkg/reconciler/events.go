@@ -0,0 +1,196 @@
+package reconciler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	EventReasonCreated          = "Created"
+	EventReasonUpdated          = "Updated"
+	EventReasonPruned           = "Pruned"
+	EventReasonDeleted          = "Deleted"
+	EventReasonApplyFailed      = "ApplyFailed"
+	EventReasonDeleteFailed     = "DeleteFailed"
+	EventReasonFinalizerRemoved = "FinalizerRemoved"
+
+	// keeps the aggregated event message readable
+	maxAggregatedObjs = 10
+	// an event is repeated after the interval, unless its message changes
+	eventRepeatInterval = 5 * time.Minute
+)
+
+// events are limited per instance across reconciliations
+var instanceEventLimiter = newEventLimiter(eventRepeatInterval)
+
+type emission struct {
+	message string
+	time    time.Time
+}
+
+// eventLimiter drops events which repeat the last message of the same
+// reason on the same object within the interval, so objects changed by
+// every reconciliation (e.g. by another controller) do not flood events
+type eventLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	now      func() time.Time
+	// last emissions by instance keys and event keys
+	last map[string]map[string]emission
+}
+
+func newEventLimiter(interval time.Duration) *eventLimiter {
+	return &eventLimiter{
+		interval: interval,
+		now:      time.Now,
+		last:     map[string]map[string]emission{},
+	}
+}
+
+func instanceKey(instance *v1alpha1.Keda) string {
+	return client.ObjectKeyFromObject(instance).String()
+}
+
+// allow reports if the event of given key and message is emitted for the instance
+func (l *eventLimiter) allow(instance *v1alpha1.Keda, key, message string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	emissions, found := l.last[instanceKey(instance)]
+	if !found {
+		emissions = map[string]emission{}
+		l.last[instanceKey(instance)] = emissions
+	}
+	last, found := emissions[key]
+	if found && last.message == message && now.Sub(last.time) < l.interval {
+		return false
+	}
+	emissions[key] = emission{message: message, time: now}
+	return true
+}
+
+// forget drops emissions of the instance, e.g. once it is deleted
+func (l *eventLimiter) forget(instance *v1alpha1.Keda) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.last, instanceKey(instance))
+}
+
+type objFailure struct {
+	obj    unstructured.Unstructured
+	reason string
+	err    error
+}
+
+// objEvents are actions on keda components taken during the reconciliation;
+// objects which did not change are not recorded, so the events are emitted
+// only if the reconciliation changes the cluster
+type objEvents struct {
+	// resource versions of live objects, empty for missing objects
+	liveVersions     map[string]string
+	actions          map[string][]unstructured.Unstructured
+	failures         []objFailure
+	finalizerRemoved bool
+}
+
+func objKey(u unstructured.Unstructured) string {
+	return fmt.Sprintf("%s %s", u.GroupVersionKind().GroupKind(), client.ObjectKeyFromObject(&u))
+}
+
+func (e *objEvents) setLiveVersion(u unstructured.Unstructured, version string) {
+	if e.liveVersions == nil {
+		e.liveVersions = map[string]string{}
+	}
+	e.liveVersions[objKey(u)] = version
+}
+
+func (e *objEvents) record(reason string, u unstructured.Unstructured) {
+	if e.actions == nil {
+		e.actions = map[string][]unstructured.Unstructured{}
+	}
+	e.actions[reason] = append(e.actions[reason], u)
+}
+
+// applied records the applied object, if it was created or updated by the apply
+func (e *objEvents) applied(u unstructured.Unstructured) {
+	version, found := e.liveVersions[objKey(u)]
+	switch {
+	case !found:
+		return
+	case version == "":
+		e.record(EventReasonCreated, u)
+	case version != u.GetResourceVersion():
+		e.record(EventReasonUpdated, u)
+	}
+}
+
+func (e *objEvents) failed(reason string, u unstructured.Unstructured, err error) {
+	e.failures = append(e.failures, objFailure{obj: u, reason: reason, err: err})
+}
+
+func aggregatedMessage(reason string, objs []unstructured.Unstructured) string {
+	names := make([]string, 0, maxAggregatedObjs)
+	for i, obj := range objs {
+		if i == maxAggregatedObjs {
+			names = append(names, fmt.Sprintf("and %d more", len(objs)-i))
+			break
+		}
+		names = append(names, objKey(obj))
+	}
+	return fmt.Sprintf("%s %d objects: %s", strings.ToLower(reason), len(objs), strings.Join(names, ", "))
+}
+
+// emit records events on affected objects and aggregated events on the instance;
+// events repeating the last message are dropped by the limiter
+func (e *objEvents) emit(recorder record.EventRecorder, limiter *eventLimiter, instance *v1alpha1.Keda) {
+	if recorder == nil {
+		return
+	}
+
+	for _, reason := range []string{EventReasonCreated, EventReasonUpdated, EventReasonPruned, EventReasonDeleted} {
+		objs := e.actions[reason]
+		if len(objs) == 0 {
+			continue
+		}
+		for i := range objs {
+			msg := fmt.Sprintf("%s by %s/%s", strings.ToLower(reason), instance.Namespace, instance.Name)
+			if limiter.allow(instance, reason+" "+objKey(objs[i]), msg) {
+				recorder.Event(&objs[i], corev1.EventTypeNormal, reason, msg)
+			}
+		}
+		msg := aggregatedMessage(reason, objs)
+		if limiter.allow(instance, reason, msg) {
+			recorder.Event(instance, corev1.EventTypeNormal, reason, msg)
+		}
+	}
+
+	for i := range e.failures {
+		failure := e.failures[i]
+		if !limiter.allow(instance, failure.reason+" "+objKey(failure.obj), failure.err.Error()) {
+			continue
+		}
+		recorder.Event(&failure.obj, corev1.EventTypeWarning, failure.reason, failure.err.Error())
+		recorder.Eventf(instance, corev1.EventTypeWarning, failure.reason,
+			"%s: %s", objKey(failure.obj), failure.err.Error())
+	}
+
+	if e.finalizerRemoved {
+		recorder.Event(instance, corev1.EventTypeNormal, EventReasonFinalizerRemoved, "keda components removed")
+		limiter.forget(instance)
+	}
+}
@@ -0,0 +1,131 @@
+package reconciler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+)
+
+func testEventObj(kind, name, resourceVersion string) unstructured.Unstructured {
+	u := testValidateObj("v1", kind, name)
+	u.SetResourceVersion(resourceVersion)
+	return u
+}
+
+func Test_objEvents_emit(t *testing.T) {
+	created := testEventObj("ServiceAccount", operatorName, "1")
+	updated := testEventObj("Service", matricsServerName, "3")
+	unchanged := testEventObj("ConfigMap", "unchanged", "5")
+	unknown := testEventObj("Secret", "unknown", "7")
+	failed := testEventObj("Deployment", operatorName, "")
+
+	var events objEvents
+	events.setLiveVersion(created, "")
+	events.setLiveVersion(updated, "2")
+	events.setLiveVersion(unchanged, "5")
+
+	for _, obj := range []unstructured.Unstructured{created, updated, unchanged, unknown} {
+		events.applied(obj)
+	}
+	events.failed(EventReasonApplyFailed, failed, errors.New("test error"))
+
+	recorder := record.NewFakeRecorder(10)
+	instance := v1alpha1.Keda{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "kyma-system"},
+	}
+	events.emit(recorder, nil, &instance)
+	close(recorder.Events)
+
+	var result []string
+	for event := range recorder.Events {
+		result = append(result, event)
+	}
+	require.Equal(t, []string{
+		"Normal Created created by kyma-system/default",
+		"Normal Created created 1 objects: ServiceAccount kyma-system/keda-manager",
+		"Normal Updated updated by kyma-system/default",
+		"Normal Updated updated 1 objects: Service kyma-system/keda-manager-metrics-apiserver",
+		"Warning ApplyFailed test error",
+		"Warning ApplyFailed Deployment kyma-system/keda-manager: test error",
+	}, result)
+}
+
+func Test_objEvents_emit_limited(t *testing.T) {
+	now := time.Now()
+	limiter := newEventLimiter(time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	instance := v1alpha1.Keda{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "kyma-system"},
+	}
+	emit := func(objs ...unstructured.Unstructured) []string {
+		var events objEvents
+		for _, obj := range objs {
+			events.setLiveVersion(obj, "1")
+			events.applied(obj)
+		}
+		recorder := record.NewFakeRecorder(10)
+		events.emit(recorder, limiter, &instance)
+		close(recorder.Events)
+
+		var result []string
+		for event := range recorder.Events {
+			result = append(result, event)
+		}
+		return result
+	}
+
+	updated := testEventObj("Service", matricsServerName, "2")
+	other := testEventObj("ServiceAccount", operatorName, "2")
+	require.Len(t, emit(updated), 2)
+
+	// the same update is dropped within the interval
+	require.Empty(t, emit(updated))
+
+	// the aggregated message changes with another object
+	require.Equal(t, []string{
+		"Normal Updated updated by kyma-system/default",
+		"Normal Updated updated 2 objects: Service kyma-system/keda-manager-metrics-apiserver, ServiceAccount kyma-system/keda-manager",
+	}, emit(updated, other))
+
+	// the same update is emitted again after the interval
+	now = now.Add(time.Minute)
+	require.Len(t, emit(updated), 2)
+
+	// emissions of a deleted instance are forgotten
+	limiter.forget(&instance)
+	require.Len(t, emit(updated), 2)
+}
+
+func Test_eventLimiter_allow(t *testing.T) {
+	limiter := newEventLimiter(time.Minute)
+	first := v1alpha1.Keda{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "kyma-system"}}
+	second := v1alpha1.Keda{ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "kyma-system"}}
+
+	require.True(t, limiter.allow(&first, EventReasonApplyFailed, "test error"))
+	require.False(t, limiter.allow(&first, EventReasonApplyFailed, "test error"))
+	require.True(t, limiter.allow(&first, EventReasonApplyFailed, "other error"))
+	require.True(t, limiter.allow(&first, EventReasonDeleteFailed, "other error"))
+	require.True(t, limiter.allow(&second, EventReasonApplyFailed, "test error"))
+
+	var nilLimiter *eventLimiter
+	require.True(t, nilLimiter.allow(&first, EventReasonApplyFailed, "test error"))
+	require.True(t, nilLimiter.allow(&first, EventReasonApplyFailed, "test error"))
+}
+
+func Test_aggregatedMessage(t *testing.T) {
+	var objs []unstructured.Unstructured
+	for i := 0; i < maxAggregatedObjs+2; i++ {
+		objs = append(objs, testEventObj("ConfigMap", "test", ""))
+	}
+
+	msg := aggregatedMessage(EventReasonDeleted, objs)
+	require.Contains(t, msg, "deleted 12 objects: ")
+	require.Contains(t, msg, ", and 2 more")
+}
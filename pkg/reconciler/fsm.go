@@ -7,14 +7,21 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/metrics"
 	"github.com/kyma-project/keda-manager/pkg/reconciler/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	apirt "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -22,6 +29,10 @@ import (
 
 type stateFn func(context.Context, *fsm, *systemState) (stateFn, *ctrl.Result, error)
 
+// RenderFunc renders module component parts into given namespace with
+// values of given Keda spec
+type RenderFunc func(spec v1alpha1.KedaSpec, namespace string) ([]unstructured.Unstructured, error)
+
 // module specific configuuration
 type Cfg struct {
 	// the Finalizer identifies the module and is is used to delete
@@ -30,6 +41,22 @@ type Cfg struct {
 	// the objects are module component parts; objects are applied
 	// on the cluster one by one with given order
 	Objs []unstructured.Unstructured
+	// the Render function renders the objects again with values of the
+	// Keda instance before they are updated; objects are used as they
+	// were loaded if it is nil
+	Render RenderFunc
+	// the Namespace module component parts are installed in, unless
+	// the Keda instance specifies its own target namespace
+	Namespace string
+	// the DryRun mode reports changes the module would apply on the
+	// cluster, without applying them, for all Keda instances
+	DryRun bool
+	// the KedaNamespace is the only namespace Keda instances are
+	// reconciled in; instances are accepted in all namespaces if empty
+	KedaNamespace string
+	// the MissingPermissions of keda-manager found at startup; keda
+	// components are not reconciled until they are granted
+	MissingPermissions []string
 }
 
 var (
@@ -64,6 +91,10 @@ func (c *Cfg) firstUnstructed(p predicate) (*unstructured.Unstructured, error) {
 	return nil, fmt.Errorf("%w: no object for given predicate", ErrNotFound)
 }
 
+func (c *Cfg) isMisplaced(k *v1alpha1.Keda) bool {
+	return c.KedaNamespace != "" && k.Namespace != c.KedaNamespace
+}
+
 func (c *Cfg) kedaManagerDeployment() (*unstructured.Unstructured, error) {
 	return c.firstUnstructed(isKedaOperatorDeployment)
 }
@@ -104,8 +135,28 @@ type systemState struct {
 	// the state of module component parts on cluster used detect
 	// module readiness
 	objs []unstructured.Unstructured
+	// the namespace module component parts are installed in
+	namespace string
 
 	snapshot v1alpha1.Status
+	// actions on module component parts reported with events
+	events objEvents
+	// the instance is reconciled again after given duration, unless
+	// the reconciliation requests otherwise
+	requeueAfter time.Duration
+	// live objects without drift, by keys of objects; they are not
+	// patched if they were applied with the desired content
+	live map[string]unstructured.Unstructured
+}
+
+// requeueWithin makes sure the instance is reconciled again within given duration
+func (s *systemState) requeueWithin(d time.Duration) {
+	if d <= 0 {
+		d = time.Second
+	}
+	if s.requeueAfter == 0 || d < s.requeueAfter {
+		s.requeueAfter = d
+	}
 }
 
 func (s *systemState) saveKedaStatus() {
@@ -117,6 +168,8 @@ func (s *systemState) saveKedaStatus() {
 }
 
 const (
+	tracerName = "github.com/kyma-project/keda-manager/pkg/reconciler"
+
 	operatorName      = "keda-manager"
 	matricsServerName = "keda-manager-metrics-apiserver"
 )
@@ -147,6 +200,7 @@ var (
 type K8s struct {
 	client.Client
 	record.EventRecorder
+	Discovery discovery.DiscoveryInterface
 }
 
 type Fsm interface {
@@ -172,7 +226,44 @@ func (m *fsm) stateFnName() string {
 	return shortName
 }
 
+// objAttributes describe the object in spans of state functions
+func objAttributes(obj unstructured.Unstructured) trace.SpanStartEventOption {
+	return trace.WithAttributes(
+		attribute.String("object.gvk", obj.GroupVersionKind().String()),
+		attribute.String("object.namespace", obj.GetNamespace()),
+		attribute.String("object.name", obj.GetName()),
+	)
+}
+
+// runStateFn runs the current state function in its own span
+func (m *fsm) runStateFn(ctx context.Context, s *systemState) (*ctrl.Result, error) {
+	stateFnName := m.stateFnName()
+	m.log.Info(fmt.Sprintf("switching state: %s", stateFnName))
+
+	ctx, span := otel.Tracer(tracerName).Start(ctx, stateFnName)
+	defer span.End()
+
+	start := time.Now()
+	var result *ctrl.Result
+	var err error
+	m.fn, result, err = m.fn(ctx, m, s)
+	metrics.ObserveStateFnDuration(stateFnName, time.Since(start))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
+
 func (m *fsm) Run(ctx context.Context, v v1alpha1.Keda) (ctrl.Result, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "reconcile", trace.WithAttributes(
+		attribute.String("keda.name", v.Name),
+		attribute.String("keda.namespace", v.Namespace),
+		attribute.Int64("keda.generation", v.Generation),
+	))
+	defer span.End()
+
 	state := systemState{instance: v}
 	var err error
 	var result *ctrl.Result
@@ -183,11 +274,23 @@ loop:
 			err = ctx.Err()
 			break loop
 		default:
-			m.log.Info(fmt.Sprintf("switching state: %s", m.stateFnName()))
-			m.fn, result, err = m.fn(ctx, m, &state)
+			result, err = m.runStateFn(ctx, &state)
 		}
 	}
 
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.String("keda.state", state.instance.Status.State))
+
+	state.events.emit(m.EventRecorder, instanceEventLimiter, &state.instance)
+
+	// state of the deleted instance is not reported anymore
+	if state.instance.GetDeletionTimestamp().IsZero() {
+		metrics.SetState(state.instance.Namespace, state.instance.Name, state.instance.Status.State)
+	}
+
 	m.log.With("error", err).
 		With("result", result).
 		Info("reconciliation done")
@@ -197,11 +300,20 @@ loop:
 	}
 
 	return ctrl.Result{
-		Requeue: false,
+		Requeue:      false,
+		RequeueAfter: state.requeueAfter,
 	}, err
 }
 
 func NewFsm(log *zap.SugaredLogger, cfg Cfg, k8s K8s) Fsm {
+	// objects are updated with the instance data during reconciliation,
+	// so every run has to start with the objects as they were loaded
+	objs := make([]unstructured.Unstructured, len(cfg.Objs))
+	for i := range cfg.Objs {
+		cfg.Objs[i].DeepCopyInto(&objs[i])
+	}
+	cfg.Objs = objs
+
 	return &fsm{
 		fn:  sFnTakeSnapshot,
 		Cfg: cfg,
@@ -50,6 +50,11 @@ func Test_updateObj_convert_errors(t *testing.T) {
 		},
 	}
 
+	defer func() {
+		toUnstructed = apirt.DefaultUnstructuredConverter.ToUnstructured
+		fromUnstructured = apirt.DefaultUnstructuredConverter.FromUnstructured
+	}()
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			toUnstructed = tt.args.toUnstructed
@@ -2,13 +2,22 @@ package reconciler
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 func sFnInitialize(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	if r.isMisplaced(&s.instance) {
+		return switchState(sFnMisplaced)
+	}
+
 	instanceIsBeingDeleted := !s.instance.GetDeletionTimestamp().IsZero()
 	instanceHasFinalizer := controllerutil.ContainsFinalizer(&s.instance, r.Finalizer)
 
@@ -39,5 +48,63 @@ func sFnInitialize(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.
 	if instanceIsBeingDeleted {
 		return switchState(sFnDeleteResources)
 	}
-	return switchState(sFnUpdateKedaDeployment)
+	if s.instance.Spec.Paused {
+		return switchState(sFnPaused)
+	}
+	// instance has been resumed, keda components are reconciled again
+	meta.RemoveStatusCondition(&s.instance.Status.Conditions, string(v1alpha1.ConditionTypePaused))
+	if len(r.MissingPermissions) > 0 {
+		return switchState(sFnMissingPermissions)
+	}
+	return switchState(sFnRender)
+}
+
+// sFnMissingPermissions reports permissions keda-manager needs to
+// reconcile keda components; they are checked again on restart
+func sFnMissingPermissions(_ context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	err := fmt.Errorf("keda-manager is missing permissions: %s", strings.Join(r.MissingPermissions, ", "))
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonMissingPermissions,
+		err,
+	)
+	return stopWithErrorAnNoRequeue(err)
+}
+
+// sFnMisplaced reports instances created outside of the keda-manager
+// namespace; they do not manage keda components, so they are deleted
+// without removing them
+func sFnMisplaced(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	if controllerutil.ContainsFinalizer(&s.instance, r.Finalizer) {
+		r.log.Debug("removing finalizer of misplaced instance")
+		controllerutil.RemoveFinalizer(&s.instance, r.Finalizer)
+
+		err := r.Update(ctx, &s.instance)
+		if client.IgnoreNotFound(err) != nil {
+			return stopWithErrorAnNoRequeue(err)
+		}
+	}
+
+	if !s.instance.GetDeletionTimestamp().IsZero() {
+		return nil, nil, nil
+	}
+
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonMisplacedInstance,
+		fmt.Errorf("keda instance must be created in the %s namespace", r.KedaNamespace),
+	)
+	return stopWithNoRequeue()
+}
+
+// sFnPaused leaves keda components as they are on the cluster
+func sFnPaused(_ context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	r.log.Debug("reconciliation paused")
+	s.instance.UpdateCondition(
+		v1alpha1.ConditionTypePaused,
+		metav1.ConditionTrue,
+		v1alpha1.ConditionReasonPaused,
+		"reconciliation paused",
+	)
+	return stopWithNoRequeue()
 }
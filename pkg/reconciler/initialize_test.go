@@ -0,0 +1,115 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func Test_sFnInitialize_paused(t *testing.T) {
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{Finalizer: v1alpha1.Finalizer},
+	}
+	s := &systemState{
+		instance: v1alpha1.Keda{
+			ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{v1alpha1.Finalizer},
+			},
+			Spec: v1alpha1.KedaSpec{Paused: true},
+		},
+	}
+
+	fn, _, err := sFnInitialize(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, fnName(sFnPaused), fnName(fn))
+
+	_, _, err = sFnPaused(context.Background(), r, s)
+	require.NoError(t, err)
+	condition := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypePaused))
+	require.NotNil(t, condition)
+	require.Equal(t, metav1.ConditionTrue, condition.Status)
+
+	// resumed instance is reconciled again
+	s.instance.Spec.Paused = false
+	fn, _, err = sFnInitialize(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, fnName(sFnRender), fnName(fn))
+	require.Nil(t, meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypePaused)))
+}
+
+func Test_sFnInitialize_missingPermissions(t *testing.T) {
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{
+			Finalizer:          v1alpha1.Finalizer,
+			MissingPermissions: []string{"escalate clusterroles.rbac.authorization.k8s.io/keda-manager"},
+		},
+	}
+	s := &systemState{
+		instance: v1alpha1.Keda{
+			ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{v1alpha1.Finalizer},
+			},
+		},
+	}
+
+	fn, _, err := sFnInitialize(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, fnName(sFnMissingPermissions), fnName(fn))
+
+	_, _, err = sFnMissingPermissions(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+	condition := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypeInstalled))
+	require.NotNil(t, condition)
+	require.Equal(t, string(v1alpha1.ConditionReasonMissingPermissions), condition.Reason)
+	require.Contains(t, condition.Message, "escalate clusterroles.rbac.authorization.k8s.io/keda-manager")
+}
+
+func Test_sFnInitialize_misplaced(t *testing.T) {
+	instance := v1alpha1.Keda{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test",
+			Namespace:  "default",
+			Finalizers: []string{v1alpha1.Finalizer},
+		},
+	}
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&instance).Build()
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{
+			Finalizer:     v1alpha1.Finalizer,
+			KedaNamespace: "kyma-system",
+		},
+		K8s: K8s{Client: c},
+	}
+	s := &systemState{instance: instance}
+
+	fn, _, err := sFnInitialize(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, fnName(sFnMisplaced), fnName(fn))
+
+	_, _, err = sFnMisplaced(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+	condition := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypeInstalled))
+	require.NotNil(t, condition)
+	require.Equal(t, string(v1alpha1.ConditionReasonMisplacedInstance), condition.Reason)
+
+	// keda components are not removed with misplaced instances
+	var updated v1alpha1.Keda
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(&instance), &updated))
+	require.False(t, controllerutil.ContainsFinalizer(&updated, v1alpha1.Finalizer))
+}
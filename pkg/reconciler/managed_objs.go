@@ -0,0 +1,34 @@
+package reconciler
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func managedObj(apiVersion, kind, name, namespace string) unstructured.Unstructured {
+	var u unstructured.Unstructured
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetName(name)
+	u.SetNamespace(namespace)
+	return u
+}
+
+// ManagedObjs returns all kinds of objects keda-manager manages while
+// reconciling given keda objects in given namespace, used to derive
+// permissions of keda-manager
+func ManagedObjs(objs []unstructured.Unstructured, namespace string) ([]unstructured.Unstructured, error) {
+	generated, err := generatedObjs(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	result := append([]unstructured.Unstructured{}, objs...)
+	result = append(result, generated...)
+	return append(result,
+		// trigger authentications are named after the instance spec
+		triggerAuthenticationObj(""),
+		managedObj("v1", "Namespace", namespace, ""),
+		// dry run results
+		managedObj("v1", "ConfigMap", "", namespace),
+	), nil
+}
@@ -0,0 +1,142 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	monitoringAPIVersion = "monitoring.coreos.com/v1"
+)
+
+var (
+	monitoringCRDs = []string{
+		"servicemonitors.monitoring.coreos.com",
+		"podmonitors.monitoring.coreos.com",
+	}
+
+	isMonitor predicate = func(u unstructured.Unstructured) bool {
+		return u.GetAPIVersion() == monitoringAPIVersion &&
+			(u.GetKind() == "PodMonitor" || u.GetKind() == "ServiceMonitor")
+	}
+)
+
+// monitoringObjs returns monitors rendered from the chart in the monitors
+// mode, used to remove them
+func monitoringObjs(namespace string) []unstructured.Unstructured {
+	result := []unstructured.Unstructured{{}, {}}
+	result[0].SetGroupVersionKind(schema.FromAPIVersionAndKind(monitoringAPIVersion, "PodMonitor"))
+	result[0].SetName(operatorName)
+	result[1].SetGroupVersionKind(schema.FromAPIVersionAndKind(monitoringAPIVersion, "ServiceMonitor"))
+	result[1].SetName(matricsServerName)
+	for i := range result {
+		result[i].SetNamespace(namespace)
+	}
+	return result
+}
+
+// crdsInstalled checks if all CRDs with given names are installed
+func crdsInstalled(ctx context.Context, c client.Client, names []string) (bool, error) {
+	for _, name := range names {
+		var crd apiextensionsv1.CustomResourceDefinition
+		err := c.Get(ctx, types.NamespacedName{Name: name}, &crd)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func monitoringMode(ctx context.Context, r *fsm, monitoring *v1alpha1.Monitoring) (v1alpha1.MonitoringMode, error) {
+	installed, err := crdsInstalled(ctx, r.Client, monitoringCRDs)
+	if err != nil {
+		return "", err
+	}
+
+	if monitoring.Mode == nil {
+		if installed {
+			return v1alpha1.MonitoringModeMonitors, nil
+		}
+		return v1alpha1.MonitoringModeAnnotations, nil
+	}
+
+	if *monitoring.Mode == v1alpha1.MonitoringModeMonitors && !installed {
+		return "", fmt.Errorf("%w: monitoring.coreos.com CRDs", ErrNotFound)
+	}
+	return *monitoring.Mode, nil
+}
+
+func hasMonitors(objs []unstructured.Unstructured) bool {
+	for _, obj := range objs {
+		if isMonitor(obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// withMonitoringMode returns the spec with the resolved monitoring mode, as
+// monitors are rendered only if their CRDs exist on the cluster
+func withMonitoringMode(ctx context.Context, r *fsm, spec v1alpha1.KedaSpec) (v1alpha1.KedaSpec, error) {
+	if spec.Monitoring == nil || !spec.Monitoring.Enabled {
+		return spec, nil
+	}
+
+	mode, err := monitoringMode(ctx, r, spec.Monitoring)
+	if err != nil {
+		return spec, err
+	}
+	monitoring := *spec.Monitoring
+	monitoring.Mode = &mode
+	spec.Monitoring = &monitoring
+	return spec, nil
+}
+
+// sFnUpdateMonitoring tracks monitors rendered from the chart and removes
+// them once they are not rendered anymore
+func sFnUpdateMonitoring(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	if !hasMonitors(r.Objs) {
+		return removeMonitoring(ctx, r, s)
+	}
+
+	// monitors are applied together with keda components
+	if !r.isDryRun(&s.instance) {
+		s.instance.Status.MonitorsCreated = true
+	}
+	return switchState(sFnUpdateNetworkPolicies)
+}
+
+// removeMonitoring deletes monitors, if they were created
+func removeMonitoring(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	// dry run must not mutate the cluster
+	if !s.instance.Status.MonitorsCreated || r.isDryRun(&s.instance) {
+		return switchState(sFnUpdateNetworkPolicies)
+	}
+
+	if err := deleteGeneratedObjs(ctx, r, s, monitoringObjs(s.namespace), EventReasonPruned); err != nil {
+		return stopWithMonitoringErr(s, err)
+	}
+	s.instance.Status.MonitorsCreated = false
+	return switchState(sFnUpdateNetworkPolicies)
+}
+
+func stopWithMonitoringErr(s *systemState, err error) (stateFn, *ctrl.Result, error) {
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonMonitoringErr,
+		err,
+	)
+	return stopWithErrorAnNoRequeue(err)
+}
@@ -0,0 +1,160 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testMonitoringFsm(t *testing.T, objs ...client.Object) *fsm {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, apiextensionsv1.AddToScheme(scheme))
+
+	return &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{
+			Objs: []unstructured.Unstructured{
+				testValidateObj("apps/v1", "Deployment", operatorName),
+				testValidateObj("apps/v1", "Deployment", matricsServerName),
+			},
+		},
+		K8s: K8s{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		},
+	}
+}
+
+func testMonitoringState(mode *v1alpha1.MonitoringMode) *systemState {
+	return &systemState{
+		namespace: "kyma-system",
+		instance: v1alpha1.Keda{
+			Spec: v1alpha1.KedaSpec{
+				Monitoring: &v1alpha1.Monitoring{
+					Enabled:  true,
+					Mode:     mode,
+					Interval: pointerTo("30s"),
+				},
+			},
+		},
+	}
+}
+
+func pointerTo[T any](v T) *T {
+	return &v
+}
+
+func testMonitoringCRDs() []client.Object {
+	var crds []client.Object
+	for _, name := range monitoringCRDs {
+		crds = append(crds, &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+		})
+	}
+	return crds
+}
+
+func Test_withMonitoringMode(t *testing.T) {
+	t.Run("annotations without monitoring crds", func(t *testing.T) {
+		r := testMonitoringFsm(t)
+		s := testMonitoringState(nil)
+
+		spec, err := withMonitoringMode(context.Background(), r, s.instance.Spec)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.MonitoringModeAnnotations, *spec.Monitoring.Mode)
+		// the instance spec is kept
+		require.Nil(t, s.instance.Spec.Monitoring.Mode)
+	})
+
+	t.Run("monitors with monitoring crds", func(t *testing.T) {
+		r := testMonitoringFsm(t, testMonitoringCRDs()...)
+		s := testMonitoringState(nil)
+
+		spec, err := withMonitoringMode(context.Background(), r, s.instance.Spec)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.MonitoringModeMonitors, *spec.Monitoring.Mode)
+		require.Equal(t, "30s", *spec.Monitoring.Interval)
+	})
+
+	t.Run("monitors without monitoring crds", func(t *testing.T) {
+		r := testMonitoringFsm(t)
+		r.Render = func(v1alpha1.KedaSpec, string) ([]unstructured.Unstructured, error) {
+			return r.Objs, nil
+		}
+		s := testMonitoringState(pointerTo(v1alpha1.MonitoringModeMonitors))
+
+		_, _, err := sFnRender(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+		require.Equal(t, string(v1alpha1.ConditionReasonMonitoringErr), s.instance.Status.Conditions[0].Reason)
+	})
+}
+
+func Test_sFnUpdateMonitoring(t *testing.T) {
+	t.Run("rendered monitors", func(t *testing.T) {
+		r := testMonitoringFsm(t, testMonitoringCRDs()...)
+		r.Objs = append(r.Objs, monitoringObjs("kyma-system")...)
+		s := testMonitoringState(pointerTo(v1alpha1.MonitoringModeMonitors))
+
+		fn, _, err := sFnUpdateMonitoring(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateNetworkPolicies), fnName(fn))
+		require.True(t, s.instance.Status.MonitorsCreated)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		r := testMonitoringFsm(t)
+		c := &deleteCountingClient{Client: r.Client}
+		r.Client = c
+		s := testMonitoringState(nil)
+		s.instance.Spec.Monitoring.Enabled = false
+
+		fn, _, err := sFnUpdateMonitoring(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateNetworkPolicies), fnName(fn))
+		require.Len(t, r.Objs, 2)
+		// monitors were never created
+		require.Zero(t, c.deletes)
+	})
+
+	t.Run("not rendered after monitors were created", func(t *testing.T) {
+		r := testMonitoringFsm(t)
+		c := &deleteCountingClient{Client: r.Client}
+		r.Client = c
+		// monitors are not rendered anymore, e.g. in the annotations mode
+		s := testMonitoringState(pointerTo(v1alpha1.MonitoringModeAnnotations))
+		s.instance.Status.MonitorsCreated = true
+
+		fn, _, err := sFnUpdateMonitoring(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateNetworkPolicies), fnName(fn))
+		require.Equal(t, 2, c.deletes)
+		require.False(t, s.instance.Status.MonitorsCreated)
+
+		// monitors are removed once
+		_, _, err = sFnUpdateMonitoring(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, 2, c.deletes)
+	})
+}
+
+// deleteCountingClient counts delete requests sent to the API server
+type deleteCountingClient struct {
+	client.Client
+	deletes int
+}
+
+func (c *deleteCountingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	c.deletes++
+	return c.Client.Delete(ctx, obj, opts...)
+}
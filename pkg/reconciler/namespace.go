@@ -0,0 +1,188 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func targetNamespace(k *v1alpha1.Keda, defaultNs string) string {
+	if k != nil && k.Spec.TargetNamespace != nil && *k.Spec.TargetNamespace != "" {
+		return *k.Spec.TargetNamespace
+	}
+	return defaultNs
+}
+
+// sFnUpdateTargetNamespace relocates namespaced module component parts to the
+// target namespace; it runs before both installation and deletion so that
+// objects are always looked up in the namespace they were installed in
+func sFnUpdateTargetNamespace(_ context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	u, err := r.kedaManagerDeployment()
+	if err != nil {
+		s.instance.UpdateStateFromErr(
+			v1alpha1.ConditionTypeInstalled,
+			v1alpha1.ConditionReasonNamespaceErr,
+			err,
+		)
+		return stopWithErrorAnNoRequeue(err)
+	}
+
+	from := u.GetNamespace()
+	s.namespace = targetNamespace(&s.instance, r.Namespace)
+	if s.namespace == "" {
+		s.namespace = from
+	}
+	// keda components are deleted from the namespace they were last applied
+	// in, which differs from the spec if it was changed since then
+	if !s.instance.GetDeletionTimestamp().IsZero() && s.instance.Status.TargetNamespace != "" {
+		s.namespace = s.instance.Status.TargetNamespace
+	}
+
+	if s.namespace != from {
+		r.log.
+			With("from", from).
+			With("to", s.namespace).
+			Debug("relocating objects")
+
+		if err := relocateObjs(r.Objs, from, s.namespace); err != nil {
+			s.instance.UpdateStateFromErr(
+				v1alpha1.ConditionTypeInstalled,
+				v1alpha1.ConditionReasonNamespaceErr,
+				err,
+			)
+			return stopWithErrorAnNoRequeue(err)
+		}
+	}
+	return switchState(sFnInitialize)
+}
+
+// relocateObjs moves all objects living in the from namespace to the to namespace
+// and rewrites references pointing to the from namespace
+func relocateObjs(objs []unstructured.Unstructured, from, to string) error {
+	for i := range objs {
+		if objs[i].GetNamespace() == from {
+			objs[i].SetNamespace(to)
+		}
+
+		var err error
+		switch objs[i].GetKind() {
+		case "RoleBinding", "ClusterRoleBinding":
+			err = relocateSubjects(&objs[i], from, to)
+		case "APIService":
+			err = relocateServiceRef(&objs[i], from, to)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func relocateSubjects(u *unstructured.Unstructured, from, to string) error {
+	subjects, found, err := unstructured.NestedSlice(u.Object, "subjects")
+	if err != nil || !found {
+		return err
+	}
+
+	for i := range subjects {
+		subject, ok := subjects[i].(map[string]interface{})
+		if !ok || subject["namespace"] != from {
+			continue
+		}
+		subject["namespace"] = to
+	}
+	return unstructured.SetNestedSlice(u.Object, subjects, "subjects")
+}
+
+func relocateServiceRef(u *unstructured.Unstructured, from, to string) error {
+	ns, found, err := unstructured.NestedString(u.Object, "spec", "service", "namespace")
+	if err != nil || !found || ns != from {
+		return err
+	}
+	return unstructured.SetNestedField(u.Object, to, "spec", "service", "namespace")
+}
+
+// sFnEnsureNamespace creates the target namespace if it does not exist yet;
+// the created namespace is labelled, but left in place once keda components
+// are deleted or moved, as it may hold other workloads
+func sFnEnsureNamespace(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	// dry run must not mutate the cluster
+	if r.isDryRun(&s.instance) {
+		return switchState(sFnDryRunApply)
+	}
+
+	ns := corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: s.namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/created-by": operatorName,
+			},
+		},
+	}
+
+	err := r.Create(ctx, &ns)
+	if err == nil {
+		r.log.With("ns", s.namespace).Debug("namespace created")
+	}
+
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		s.instance.UpdateStateFromErr(
+			v1alpha1.ConditionTypeInstalled,
+			v1alpha1.ConditionReasonNamespaceErr,
+			err,
+		)
+		return stopWithErrorAnNoRequeue(err)
+	}
+	return switchState(sFnDetectDrift)
+}
+
+// sFnPruneTargetNamespace removes module component parts left in the namespace
+// keda components were applied in before the target namespace was changed;
+// the previous namespace itself is left in place
+func sFnPruneTargetNamespace(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	previous := s.instance.Status.TargetNamespace
+	if previous != "" && previous != s.namespace {
+		r.log.
+			With("from", previous).
+			With("to", s.namespace).
+			Debug("pruning objects")
+
+		if err := pruneNamespace(ctx, r, s, previous); err != nil {
+			s.instance.UpdateStateFromErr(
+				v1alpha1.ConditionTypeInstalled,
+				v1alpha1.ConditionReasonNamespaceErr,
+				err,
+			)
+			return stopWithErrorAnNoRequeue(err)
+		}
+	}
+
+	s.instance.Status.TargetNamespace = s.namespace
+	return switchState(sFnVerify)
+}
+
+// pruneNamespace deletes objects applied in the target namespace, and all
+// objects which can be generated from the instance spec, from given namespace
+func pruneNamespace(ctx context.Context, r *fsm, s *systemState, namespace string) error {
+	var objs []unstructured.Unstructured
+	for _, obj := range r.Objs {
+		if obj.GetNamespace() != s.namespace {
+			continue
+		}
+		obj := *obj.DeepCopy()
+		obj.SetNamespace(namespace)
+		objs = append(objs, obj)
+	}
+
+	generated, err := generatedObjs(namespace)
+	if err != nil {
+		return err
+	}
+	return deleteGeneratedObjs(ctx, r, s, append(objs, generated...), EventReasonPruned)
+}
@@ -0,0 +1,263 @@
+package reconciler
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/yaml"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testNamespaceObjs() []unstructured.Unstructured {
+	return []unstructured.Unstructured{
+		{
+			Object: map[string]interface{}{
+				"kind":       "Deployment",
+				"apiVersion": "apps/v1",
+				"metadata": map[string]interface{}{
+					"name":      operatorName,
+					"namespace": "kyma-system",
+				},
+			},
+		},
+		{
+			Object: map[string]interface{}{
+				"kind":       "RoleBinding",
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"metadata": map[string]interface{}{
+					"name":      "auth-reader",
+					"namespace": "kube-system",
+				},
+				"subjects": []interface{}{
+					map[string]interface{}{
+						"kind":      "ServiceAccount",
+						"name":      operatorName,
+						"namespace": "kyma-system",
+					},
+				},
+			},
+		},
+		{
+			Object: map[string]interface{}{
+				"kind":       "ClusterRoleBinding",
+				"apiVersion": "rbac.authorization.k8s.io/v1",
+				"metadata": map[string]interface{}{
+					"name": "hpa-controller",
+				},
+				"subjects": []interface{}{
+					map[string]interface{}{
+						"kind":      "ServiceAccount",
+						"name":      "horizontal-pod-autoscaler",
+						"namespace": "kube-system",
+					},
+				},
+			},
+		},
+		{
+			Object: map[string]interface{}{
+				"kind":       "APIService",
+				"apiVersion": "apiregistration.k8s.io/v1",
+				"metadata": map[string]interface{}{
+					"name": "v1beta1.external.metrics.k8s.io",
+				},
+				"spec": map[string]interface{}{
+					"service": map[string]interface{}{
+						"name":      matricsServerName,
+						"namespace": "kyma-system",
+					},
+				},
+			},
+		},
+	}
+}
+
+func Test_relocateObjs(t *testing.T) {
+	objs := testNamespaceObjs()
+
+	err := relocateObjs(objs, "kyma-system", "keda")
+	require.NoError(t, err)
+
+	require.Equal(t, "keda", objs[0].GetNamespace())
+	// objects from other namespaces stay where they are
+	require.Equal(t, "kube-system", objs[1].GetNamespace())
+
+	subjects, _, _ := unstructured.NestedSlice(objs[1].Object, "subjects")
+	require.Equal(t, "keda", subjects[0].(map[string]interface{})["namespace"])
+
+	subjects, _, _ = unstructured.NestedSlice(objs[2].Object, "subjects")
+	require.Equal(t, "kube-system", subjects[0].(map[string]interface{})["namespace"])
+
+	ns, _, _ := unstructured.NestedString(objs[3].Object, "spec", "service", "namespace")
+	require.Equal(t, "keda", ns)
+}
+
+func Test_sFnUpdateTargetNamespace(t *testing.T) {
+	t.Run("use default namespace", func(t *testing.T) {
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{Objs: testNamespaceObjs(), Namespace: "keda"},
+		}
+		s := &systemState{}
+
+		fn, resp, err := sFnUpdateTargetNamespace(context.Background(), r, s)
+		require.Nil(t, resp)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnInitialize), fnName(fn))
+		require.Equal(t, "keda", s.namespace)
+		require.Equal(t, "keda", r.Objs[0].GetNamespace())
+	})
+
+	t.Run("use instance namespace", func(t *testing.T) {
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{Objs: testNamespaceObjs(), Namespace: "keda"},
+		}
+		s := &systemState{
+			instance: v1alpha1.Keda{
+				Spec: v1alpha1.KedaSpec{TargetNamespace: pointer.String("other")},
+			},
+		}
+
+		_, _, err := sFnUpdateTargetNamespace(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, "other", s.namespace)
+		require.Equal(t, "other", r.Objs[0].GetNamespace())
+	})
+
+	t.Run("delete from the last target namespace", func(t *testing.T) {
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{Objs: testNamespaceObjs(), Namespace: "keda"},
+		}
+		now := metav1.Now()
+		s := &systemState{
+			instance: v1alpha1.Keda{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Spec:       v1alpha1.KedaSpec{TargetNamespace: pointer.String("other")},
+				Status:     v1alpha1.Status{TargetNamespace: "previous"},
+			},
+		}
+
+		_, _, err := sFnUpdateTargetNamespace(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, "previous", s.namespace)
+		require.Equal(t, "previous", r.Objs[0].GetNamespace())
+	})
+
+	t.Run("keep manifest namespace", func(t *testing.T) {
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{Objs: testNamespaceObjs()},
+		}
+		s := &systemState{}
+
+		_, _, err := sFnUpdateTargetNamespace(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, "kyma-system", s.namespace)
+	})
+}
+
+func Test_sFnEnsureNamespace(t *testing.T) {
+	c := fake.NewClientBuilder().Build()
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		K8s: K8s{Client: c},
+	}
+	s := &systemState{namespace: "keda"}
+
+	for i := 0; i < 2; i++ {
+		fn, resp, err := sFnEnsureNamespace(context.Background(), r, s)
+		require.Nil(t, resp)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnDetectDrift), fnName(fn))
+	}
+
+	var ns corev1.Namespace
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: "keda"}, &ns))
+}
+
+func Test_NewFsm_copiesObjs(t *testing.T) {
+	file, err := os.Open("../../keda-manager.yaml")
+	require.NoError(t, err)
+	defer file.Close()
+
+	objs, err := yaml.LoadData(file)
+	require.NoError(t, err)
+
+	m := NewFsm(zap.NewNop().Sugar(), Cfg{Objs: objs}, K8s{}).(*fsm)
+	require.Equal(t, objs, m.Objs)
+
+	require.NoError(t, relocateObjs(m.Objs, "kyma-system", "keda"))
+	for _, obj := range objs {
+		require.NotEqual(t, "keda", obj.GetNamespace())
+	}
+}
+
+func Test_sFnPruneTargetNamespace(t *testing.T) {
+	testDeployment := func(namespace string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: operatorName, Namespace: namespace},
+		}
+	}
+
+	t.Run("switch namespaces", func(t *testing.T) {
+		c := fake.NewClientBuilder().
+			WithObjects(testDeployment("kyma-system"), testDeployment("keda")).
+			Build()
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{Objs: []unstructured.Unstructured{
+				testValidateObj("apps/v1", "Deployment", operatorName),
+			}},
+			K8s: K8s{Client: c},
+		}
+		require.NoError(t, relocateObjs(r.Objs, "kyma-system", "keda"))
+		s := &systemState{
+			namespace: "keda",
+			instance: v1alpha1.Keda{
+				Status: v1alpha1.Status{TargetNamespace: "kyma-system"},
+			},
+		}
+
+		fn, resp, err := sFnPruneTargetNamespace(context.Background(), r, s)
+		require.Nil(t, resp)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnVerify), fnName(fn))
+		require.Equal(t, "keda", s.instance.Status.TargetNamespace)
+		require.Len(t, s.events.actions[EventReasonPruned], 1)
+
+		var deployment appsv1.Deployment
+		err = c.Get(context.Background(), types.NamespacedName{Name: operatorName, Namespace: "kyma-system"}, &deployment)
+		require.True(t, apierrors.IsNotFound(err))
+		require.NoError(t, c.Get(context.Background(), types.NamespacedName{Name: operatorName, Namespace: "keda"}, &deployment))
+	})
+
+	t.Run("record first target namespace", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithObjects(testDeployment("keda")).Build()
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{Objs: []unstructured.Unstructured{
+				testValidateObj("apps/v1", "Deployment", operatorName),
+			}},
+			K8s: K8s{Client: c},
+		}
+		s := &systemState{namespace: "keda"}
+
+		fn, _, err := sFnPruneTargetNamespace(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnVerify), fnName(fn))
+		require.Equal(t, "keda", s.instance.Status.TargetNamespace)
+		require.Empty(t, s.events.actions[EventReasonPruned])
+	})
+}
@@ -0,0 +1,188 @@
+package reconciler
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	caBundleVolumeName = "ca-bundle"
+	caBundleMountPath  = "/etc/keda-manager/ca-bundle"
+	// system CAs of keda images; SSL_CERT_DIR replaces default directories
+	systemCertsDir = "/etc/ssl/certs"
+
+	// the checksum annotation rolls out keda pods once the CA bundle changes
+	caBundleChecksumAnnotation = "keda-manager.kyma-project.io/ca-bundle-checksum"
+)
+
+var (
+	// in-cluster destinations are never proxied; the kube-apiserver is
+	// reached with the IP of the kubernetes service
+	inClusterNoProxy = []string{"$(KUBERNETES_SERVICE_HOST)", ".svc", ".cluster.local"}
+)
+
+type networkSettings struct {
+	env      []corev1.EnvVar
+	caBundle *v1alpha1.CABundleRef
+	checksum string
+}
+
+func proxyEnv(cfg v1alpha1.NetworkCfg) []corev1.EnvVar {
+	var env []corev1.EnvVar
+	if cfg.HTTPProxy != nil {
+		env = append(env, corev1.EnvVar{Name: "HTTP_PROXY", Value: *cfg.HTTPProxy})
+	}
+	if cfg.HTTPSProxy != nil {
+		env = append(env, corev1.EnvVar{Name: "HTTPS_PROXY", Value: *cfg.HTTPSProxy})
+	}
+	if len(env) == 0 && cfg.NoProxy == nil {
+		return nil
+	}
+
+	noProxy := inClusterNoProxy
+	if cfg.NoProxy != nil && *cfg.NoProxy != "" {
+		noProxy = append([]string{*cfg.NoProxy}, noProxy...)
+	}
+	return append(env, corev1.EnvVar{Name: "NO_PROXY", Value: strings.Join(noProxy, ",")})
+}
+
+// setEnv overrides the env with given name, or adds it
+func setEnv(container *corev1.Container, env corev1.EnvVar) {
+	for i := range container.Env {
+		if container.Env[i].Name == env.Name {
+			container.Env[i] = env
+			return
+		}
+	}
+	container.Env = append(container.Env, env)
+}
+
+func caBundleVolume(ref v1alpha1.CABundleRef) corev1.Volume {
+	items := []corev1.KeyToPath{{Key: ref.Key, Path: ref.Key}}
+	if ref.Kind == v1alpha1.CABundleKindSecret {
+		return corev1.Volume{
+			Name: caBundleVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: ref.Name, Items: items},
+			},
+		}
+	}
+	return corev1.Volume{
+		Name: caBundleVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ref.Name},
+				Items:                items,
+			},
+		},
+	}
+}
+
+func updateDeploymentNetwork(deployment *appsv1.Deployment, settings networkSettings) error {
+	template := &deployment.Spec.Template
+	if settings.caBundle != nil {
+		template.Spec.Volumes = append(template.Spec.Volumes, caBundleVolume(*settings.caBundle))
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[caBundleChecksumAnnotation] = settings.checksum
+	}
+
+	for i := range template.Spec.Containers {
+		container := &template.Spec.Containers[i]
+		for _, env := range settings.env {
+			setEnv(container, env)
+		}
+		if settings.caBundle == nil {
+			continue
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      caBundleVolumeName,
+			MountPath: caBundleMountPath,
+			ReadOnly:  true,
+		})
+		setEnv(container, corev1.EnvVar{
+			Name:  "SSL_CERT_DIR",
+			Value: strings.Join([]string{caBundleMountPath, systemCertsDir}, ":"),
+		})
+	}
+	return nil
+}
+
+// caBundleChecksum returns the checksum of the referenced CA bundle
+func caBundleChecksum(ctx context.Context, r *fsm, namespace string, ref v1alpha1.CABundleRef) (string, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: ref.Name}
+
+	var bundle []byte
+	var found bool
+	switch ref.Kind {
+	case v1alpha1.CABundleKindSecret:
+		var secret corev1.Secret
+		if err := r.Get(ctx, key, &secret); err != nil {
+			return "", err
+		}
+		bundle, found = secret.Data[ref.Key]
+	default:
+		var configMap corev1.ConfigMap
+		if err := r.Get(ctx, key, &configMap); err != nil {
+			return "", err
+		}
+		var data string
+		data, found = configMap.Data[ref.Key]
+		bundle = []byte(data)
+	}
+
+	if !found {
+		return "", fmt.Errorf("%s %s/%s has no %s key", ref.Kind, namespace, ref.Name, ref.Key)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(bundle)), nil
+}
+
+// sFnUpdateNetwork makes keda components connect to scaler backends through
+// proxies and trust additional CAs
+func sFnUpdateNetwork(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	cfg := s.instance.Spec.Network
+	if cfg == nil {
+		return switchState(sFnUpdateEnvChecksum)
+	}
+
+	settings := networkSettings{
+		env:      proxyEnv(*cfg),
+		caBundle: cfg.CABundle,
+	}
+	if cfg.CABundle != nil {
+		checksum, err := caBundleChecksum(ctx, r, s.namespace, *cfg.CABundle)
+		if err != nil {
+			return stopWithNetworkErr(s, err)
+		}
+		settings.checksum = checksum
+	}
+
+	for _, p := range []predicate{isKedaOperatorDeployment, isKedaMatricsServerDeployment} {
+		u, err := r.firstUnstructed(p)
+		if err != nil {
+			return stopWithNetworkErr(s, err)
+		}
+		if err := updateObj(u, settings, updateDeploymentNetwork); err != nil {
+			return stopWithNetworkErr(s, err)
+		}
+	}
+	return switchState(sFnUpdateEnvChecksum)
+}
+
+func stopWithNetworkErr(s *systemState, err error) (stateFn, *ctrl.Result, error) {
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonNetworkErr,
+		err,
+	)
+	return stopWithErrorAnNoRequeue(err)
+}
@@ -0,0 +1,225 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apirt "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	metricsServerSecurePort = 6443
+	metricsServerHTTPPort   = 8080
+	// the chart default port of metrics server metrics, exposed by monitoring
+	metricsServerMetricsPort = 9022
+)
+
+var (
+	// egress every keda component needs to work
+	dnsPorts       = []int32{53}
+	apiServerPorts = []int32{443, 6443}
+)
+
+func networkPolicyPort(port int32, protocol corev1.Protocol) networkingv1.NetworkPolicyPort {
+	p := intstr.FromInt(int(port))
+	return networkingv1.NetworkPolicyPort{
+		Port:     &p,
+		Protocol: &protocol,
+	}
+}
+
+func networkPolicyPeers(cidrs []string) []networkingv1.NetworkPolicyPeer {
+	var result []networkingv1.NetworkPolicyPeer
+	for _, cidr := range cidrs {
+		result = append(result, networkingv1.NetworkPolicyPeer{
+			IPBlock: &networkingv1.IPBlock{CIDR: cidr},
+		})
+	}
+	return result
+}
+
+// networkPolicyEgress returns egress rules of keda components; egress is
+// restricted only if the custom egress is configured
+func networkPolicyEgress(cfg *v1alpha1.NetworkPolicyCfg) []networkingv1.NetworkPolicyEgressRule {
+	if len(cfg.Egress) == 0 {
+		return nil
+	}
+
+	var dns []networkingv1.NetworkPolicyPort
+	for _, port := range dnsPorts {
+		dns = append(dns, networkPolicyPort(port, corev1.ProtocolUDP), networkPolicyPort(port, corev1.ProtocolTCP))
+	}
+	var apiServer []networkingv1.NetworkPolicyPort
+	for _, port := range apiServerPorts {
+		apiServer = append(apiServer, networkPolicyPort(port, corev1.ProtocolTCP))
+	}
+
+	result := []networkingv1.NetworkPolicyEgressRule{
+		{Ports: dns},
+		{Ports: apiServer, To: networkPolicyPeers(cfg.APIServerCIDRs)},
+	}
+	for _, egress := range cfg.Egress {
+		rule := networkingv1.NetworkPolicyEgressRule{
+			To: networkPolicyPeers(egress.CIDRs),
+		}
+		for _, port := range egress.Ports {
+			protocol := corev1.ProtocolTCP
+			if port.Protocol != nil {
+				protocol = *port.Protocol
+			}
+			rule.Ports = append(rule.Ports, networkPolicyPort(port.Port, protocol))
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+func buildNetworkPolicy(name, namespace, app string, ingress []networkingv1.NetworkPolicyIngressRule, egress []networkingv1.NetworkPolicyEgressRule) networkingv1.NetworkPolicy {
+	policyTypes := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	if len(egress) != 0 {
+		policyTypes = append(policyTypes, networkingv1.PolicyTypeEgress)
+	}
+
+	return networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+			Kind:       "NetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of":    operatorName,
+				"app.kubernetes.io/managed-by": operatorName,
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": app},
+			},
+			PolicyTypes: policyTypes,
+			Ingress:     ingress,
+			Egress:      egress,
+		},
+	}
+}
+
+// networkPolicyObjs returns network policies of the operator and the metrics server
+func networkPolicyObjs(namespace string, cfg *v1alpha1.NetworkPolicyCfg) ([]unstructured.Unstructured, error) {
+	egress := networkPolicyEgress(cfg)
+	metrics := networkingv1.NetworkPolicyIngressRule{
+		Ports: []networkingv1.NetworkPolicyPort{
+			networkPolicyPort(metricsServerHTTPPort, corev1.ProtocolTCP),
+		},
+	}
+
+	policies := []networkingv1.NetworkPolicy{
+		buildNetworkPolicy(operatorName, namespace, operatorName,
+			[]networkingv1.NetworkPolicyIngressRule{metrics},
+			egress,
+		),
+		buildNetworkPolicy(matricsServerName, namespace, matricsServerName,
+			[]networkingv1.NetworkPolicyIngressRule{
+				{
+					Ports: []networkingv1.NetworkPolicyPort{
+						networkPolicyPort(metricsServerHTTPPort, corev1.ProtocolTCP),
+						networkPolicyPort(metricsServerMetricsPort, corev1.ProtocolTCP),
+					},
+				},
+				{
+					Ports: []networkingv1.NetworkPolicyPort{
+						networkPolicyPort(metricsServerSecurePort, corev1.ProtocolTCP),
+					},
+					From: networkPolicyPeers(cfg.APIServerCIDRs),
+				},
+			},
+			egress,
+		),
+	}
+
+	result := make([]unstructured.Unstructured, 0, len(policies))
+	for i := range policies {
+		obj, err := apirt.DefaultUnstructuredConverter.ToUnstructured(&policies[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, unstructured.Unstructured{Object: obj})
+	}
+	return result, nil
+}
+
+func isNetworkPolicyEnabled(k *v1alpha1.Keda) bool {
+	return k.Spec.NetworkPolicy != nil && k.Spec.NetworkPolicy.Enabled
+}
+
+// isMetricsAPIServiceAvailable checks if the kube-apiserver reaches the metrics server
+func isMetricsAPIServiceAvailable(objs []unstructured.Unstructured) bool {
+	for _, obj := range objs {
+		if obj.GetKind() != "APIService" {
+			continue
+		}
+
+		conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		if err != nil {
+			return false
+		}
+		for _, condition := range conditions {
+			c, ok := condition.(map[string]interface{})
+			if ok && c["type"] == "Available" && c["status"] == string(metav1.ConditionTrue) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// sFnUpdateNetworkPolicies generates network policies of keda components
+// and removes them once they are not used anymore
+func sFnUpdateNetworkPolicies(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	cfg := s.instance.Spec.NetworkPolicy
+	enabled := isNetworkPolicyEnabled(&s.instance)
+	if !enabled {
+		cfg = &v1alpha1.NetworkPolicyCfg{}
+	}
+
+	objs, err := networkPolicyObjs(s.namespace, cfg)
+	if err != nil {
+		return stopWithNetworkPolicyErr(s, err)
+	}
+
+	if enabled {
+		// network policies are applied together with keda components
+		r.Objs = append(r.Objs, objs...)
+		if !r.isDryRun(&s.instance) {
+			s.instance.Status.NetworkPoliciesCreated = true
+		}
+		return switchState(sFnUpdateCertificates)
+	}
+
+	// dry run must not mutate the cluster
+	if !s.instance.Status.NetworkPoliciesCreated || r.isDryRun(&s.instance) {
+		return switchState(sFnUpdateCertificates)
+	}
+
+	if err := deleteGeneratedObjs(ctx, r, s, objs, EventReasonPruned); err != nil {
+		return stopWithNetworkPolicyErr(s, err)
+	}
+	s.instance.Status.NetworkPoliciesCreated = false
+	return switchState(sFnUpdateCertificates)
+}
+
+func stopWithNetworkPolicyErr(s *systemState, err error) (stateFn, *ctrl.Result, error) {
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonNetworkPolicyErr,
+		err,
+	)
+	return stopWithErrorAnNoRequeue(err)
+}
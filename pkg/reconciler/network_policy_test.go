@@ -0,0 +1,145 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_networkPolicyObjs(t *testing.T) {
+	t.Run("egress not restricted", func(t *testing.T) {
+		objs, err := networkPolicyObjs("kyma-system", &v1alpha1.NetworkPolicyCfg{Enabled: true})
+		require.NoError(t, err)
+		require.Len(t, objs, 2)
+
+		for _, obj := range objs {
+			policyTypes, _, err := unstructured.NestedStringSlice(obj.Object, "spec", "policyTypes")
+			require.NoError(t, err)
+			require.Equal(t, []string{"Ingress"}, policyTypes)
+		}
+	})
+
+	t.Run("egress restricted", func(t *testing.T) {
+		udp := corev1.ProtocolUDP
+		objs, err := networkPolicyObjs("kyma-system", &v1alpha1.NetworkPolicyCfg{
+			Enabled:        true,
+			APIServerCIDRs: []string{"10.0.0.1/32"},
+			Egress: []v1alpha1.NetworkPolicyEgress{
+				{
+					CIDRs: []string{"10.1.0.0/16"},
+					Ports: []v1alpha1.NetworkPolicyPort{{Port: 5672}, {Port: 8125, Protocol: &udp}},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		var policy networkingv1.NetworkPolicy
+		require.NoError(t, fromUnstructured(objs[1].Object, &policy))
+		require.Equal(t, matricsServerName, policy.Name)
+		require.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}, policy.Spec.PolicyTypes)
+
+		// the kube-apiserver reaches the metrics server from its CIDRs
+		require.Len(t, policy.Spec.Ingress, 2)
+		require.Equal(t, "10.0.0.1/32", policy.Spec.Ingress[1].From[0].IPBlock.CIDR)
+
+		// dns, kube-apiserver and custom egress
+		require.Len(t, policy.Spec.Egress, 3)
+		require.Equal(t, "10.1.0.0/16", policy.Spec.Egress[2].To[0].IPBlock.CIDR)
+		require.Equal(t, corev1.ProtocolTCP, *policy.Spec.Egress[2].Ports[0].Protocol)
+		require.Equal(t, corev1.ProtocolUDP, *policy.Spec.Egress[2].Ports[1].Protocol)
+	})
+}
+
+func Test_sFnUpdateNetworkPolicies(t *testing.T) {
+	objs, err := networkPolicyObjs("kyma-system", &v1alpha1.NetworkPolicyCfg{})
+	require.NoError(t, err)
+	var existing networkingv1.NetworkPolicy
+	require.NoError(t, fromUnstructured(objs[0].Object, &existing))
+
+	c := fake.NewClientBuilder().WithObjects(&existing).Build()
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		K8s: K8s{Client: c},
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		s := &systemState{
+			namespace: "kyma-system",
+			instance: v1alpha1.Keda{
+				Spec: v1alpha1.KedaSpec{
+					NetworkPolicy: &v1alpha1.NetworkPolicyCfg{Enabled: true},
+				},
+			},
+		}
+
+		fn, _, err := sFnUpdateNetworkPolicies(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateCertificates), fnName(fn))
+		require.Len(t, r.Objs, 2)
+		require.True(t, s.instance.Status.NetworkPoliciesCreated)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		s := &systemState{
+			namespace: "kyma-system",
+			instance: v1alpha1.Keda{
+				Status: v1alpha1.Status{NetworkPoliciesCreated: true},
+			},
+		}
+
+		fn, _, err := sFnUpdateNetworkPolicies(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateCertificates), fnName(fn))
+		require.False(t, s.instance.Status.NetworkPoliciesCreated)
+
+		// network policies are pruned
+		var policy networkingv1.NetworkPolicy
+		err = c.Get(context.Background(), types.NamespacedName{Namespace: "kyma-system", Name: operatorName}, &policy)
+		require.Error(t, err)
+		require.NoError(t, client.IgnoreNotFound(err))
+		require.Len(t, s.events.actions[EventReasonPruned], 1)
+	})
+
+	t.Run("disabled without created network policies", func(t *testing.T) {
+		counting := &deleteCountingClient{Client: c}
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			K8s: K8s{Client: counting},
+		}
+		s := &systemState{namespace: "kyma-system"}
+
+		fn, _, err := sFnUpdateNetworkPolicies(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateCertificates), fnName(fn))
+		require.Zero(t, counting.deletes)
+	})
+}
+
+func Test_isMetricsAPIServiceAvailable(t *testing.T) {
+	apiService := unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apiregistration.k8s.io/v1",
+			"kind":       "APIService",
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Available", "status": "False"},
+				},
+			},
+		},
+	}
+	require.False(t, isMetricsAPIServiceAvailable([]unstructured.Unstructured{apiService}))
+
+	require.NoError(t, unstructured.SetNestedSlice(apiService.Object, []interface{}{
+		map[string]interface{}{"type": "Available", "status": "True"},
+	}, "status", "conditions"))
+	require.True(t, isMetricsAPIServiceAvailable([]unstructured.Unstructured{apiService}))
+}
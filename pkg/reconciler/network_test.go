@@ -0,0 +1,105 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testNetworkFsm(t *testing.T, bundle string) *fsm {
+	r := testManifestFsm(t)
+	r.Client = fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "corporate-ca", Namespace: "kyma-system"},
+		Data:       map[string]string{"ca.crt": bundle},
+	}).Build()
+	return r
+}
+
+func testNetworkState() *systemState {
+	return &systemState{
+		namespace: "kyma-system",
+		instance: v1alpha1.Keda{
+			Spec: v1alpha1.KedaSpec{
+				Network: &v1alpha1.NetworkCfg{
+					HTTPSProxy: pointerTo("http://proxy.example.com:3128"),
+					NoProxy:    pointerTo("10.0.0.0/8"),
+					CABundle: &v1alpha1.CABundleRef{
+						Kind: v1alpha1.CABundleKindConfigMap,
+						Name: "corporate-ca",
+						Key:  "ca.crt",
+					},
+				},
+			},
+		},
+	}
+}
+
+func testNetworkDeployments(t *testing.T, r *fsm) []appsv1.Deployment {
+	var result []appsv1.Deployment
+	for _, p := range []predicate{isKedaOperatorDeployment, isKedaMatricsServerDeployment} {
+		u, err := r.firstUnstructed(p)
+		require.NoError(t, err)
+		var deployment appsv1.Deployment
+		require.NoError(t, fromUnstructured(u.Object, &deployment))
+		result = append(result, deployment)
+	}
+	return result
+}
+
+func testEnvValue(container corev1.Container, name string) string {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+	return ""
+}
+
+func Test_sFnUpdateNetwork(t *testing.T) {
+	t.Run("proxies and ca bundle", func(t *testing.T) {
+		r := testNetworkFsm(t, "bundle")
+		s := testNetworkState()
+
+		fn, _, err := sFnUpdateNetwork(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateEnvChecksum), fnName(fn))
+
+		for _, deployment := range testNetworkDeployments(t, r) {
+			container := deployment.Spec.Template.Spec.Containers[0]
+			require.Equal(t, "http://proxy.example.com:3128", testEnvValue(container, "HTTPS_PROXY"))
+			require.Equal(t, "10.0.0.0/8,$(KUBERNETES_SERVICE_HOST),.svc,.cluster.local", testEnvValue(container, "NO_PROXY"))
+			require.Empty(t, testEnvValue(container, "HTTP_PROXY"))
+			require.Equal(t, "/etc/keda-manager/ca-bundle:/etc/ssl/certs", testEnvValue(container, "SSL_CERT_DIR"))
+			require.Contains(t, container.VolumeMounts, corev1.VolumeMount{
+				Name:      caBundleVolumeName,
+				MountPath: caBundleMountPath,
+				ReadOnly:  true,
+			})
+			require.NotEmpty(t, deployment.Spec.Template.Annotations[caBundleChecksumAnnotation])
+		}
+
+		// pods are rolled out again once the bundle changes
+		checksum := testNetworkDeployments(t, r)[0].Spec.Template.Annotations[caBundleChecksumAnnotation]
+		r = testNetworkFsm(t, "rotated bundle")
+		_, _, err = sFnUpdateNetwork(context.Background(), r, testNetworkState())
+		require.NoError(t, err)
+		require.NotEqual(t, checksum, testNetworkDeployments(t, r)[0].Spec.Template.Annotations[caBundleChecksumAnnotation])
+	})
+
+	t.Run("missing ca bundle", func(t *testing.T) {
+		r := testNetworkFsm(t, "bundle")
+		s := testNetworkState()
+		s.instance.Spec.Network.CABundle.Key = "missing.crt"
+
+		_, _, err := sFnUpdateNetwork(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+		require.Equal(t, string(v1alpha1.ConditionReasonNetworkErr), s.instance.Status.Conditions[0].Reason)
+	})
+}
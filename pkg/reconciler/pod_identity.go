@@ -0,0 +1,89 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var (
+	ErrPodIdentity = errors.New("invalid pod identity")
+)
+
+// validatePodIdentity returns problems of the pod identity configuration;
+// trigger authentications may only use the identity of the operator
+func validatePodIdentity(spec v1alpha1.KedaSpec) []string {
+	cfg := spec.PodIdentity
+	if cfg == nil {
+		return validateTriggerAuthPodIdentity(spec.TriggerAuthentications, v1alpha1.PodIdentityProviderNone)
+	}
+
+	settings := map[v1alpha1.PodIdentityProvider]struct {
+		field string
+		set   bool
+	}{
+		v1alpha1.PodIdentityProviderAzure:         {"azure", cfg.Azure != nil},
+		v1alpha1.PodIdentityProviderAzureWorkload: {"azureWorkload", cfg.AzureWorkload != nil},
+		v1alpha1.PodIdentityProviderAWSEKS:        {"awsEks", cfg.AWSEKS != nil},
+		v1alpha1.PodIdentityProviderGCP:           {"gcp", cfg.GCP != nil},
+	}
+
+	var problems []string
+	if _, found := settings[cfg.Provider]; !found {
+		problems = append(problems, fmt.Sprintf("provider %s is not supported", cfg.Provider))
+	}
+	// iterate providers in a stable order
+	for _, provider := range []v1alpha1.PodIdentityProvider{
+		v1alpha1.PodIdentityProviderAzure,
+		v1alpha1.PodIdentityProviderAzureWorkload,
+		v1alpha1.PodIdentityProviderAWSEKS,
+		v1alpha1.PodIdentityProviderGCP,
+	} {
+		setting := settings[provider]
+		switch {
+		case provider == cfg.Provider && !setting.set:
+			problems = append(problems, fmt.Sprintf("%s is required by the %s provider", setting.field, cfg.Provider))
+		case provider != cfg.Provider && setting.set:
+			problems = append(problems, fmt.Sprintf("%s is not allowed with the %s provider", setting.field, cfg.Provider))
+		}
+	}
+
+	return append(problems, validateTriggerAuthPodIdentity(spec.TriggerAuthentications, cfg.Provider)...)
+}
+
+// validateTriggerAuthPodIdentity returns trigger authentications using
+// a provider other than the one of the operator
+func validateTriggerAuthPodIdentity(auths []v1alpha1.TriggerAuthentication, provider v1alpha1.PodIdentityProvider) []string {
+	var problems []string
+	for _, auth := range auths {
+		if auth.PodIdentity == nil ||
+			auth.PodIdentity.Provider == v1alpha1.PodIdentityProviderNone ||
+			auth.PodIdentity.Provider == provider {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("trigger authentication %s uses the %s provider instead of %s",
+			auth.Name, auth.PodIdentity.Provider, provider))
+	}
+	return problems
+}
+
+// sFnValidatePodIdentity prevents applying keda components with a pod
+// identity the operator cannot use; the pod identity itself is rendered
+// from the chart values
+func sFnValidatePodIdentity(_ context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	if problems := validatePodIdentity(s.instance.Spec); len(problems) != 0 {
+		err := fmt.Errorf("%w: %s", ErrPodIdentity, strings.Join(problems, "; "))
+		r.log.With("err", err).Warn("keda components are not applied")
+		s.instance.UpdateStateFromErr(
+			v1alpha1.ConditionTypeInstalled,
+			v1alpha1.ConditionReasonPodIdentityErr,
+			err,
+		)
+		return stopWithNoRequeue()
+	}
+	return switchState(sFnUpdateMetricsServerDeployment)
+}
@@ -0,0 +1,155 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+)
+
+func Test_validatePodIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     v1alpha1.KedaSpec
+		problems []string
+	}{
+		{
+			name: "not configured",
+		},
+		{
+			name: "settings of the provider",
+			spec: v1alpha1.KedaSpec{
+				PodIdentity: &v1alpha1.PodIdentityCfg{
+					Provider: v1alpha1.PodIdentityProviderGCP,
+					GCP:      &v1alpha1.GCPIdentityCfg{ServiceAccount: "keda@test.iam.gserviceaccount.com"},
+				},
+				TriggerAuthentications: []v1alpha1.TriggerAuthentication{
+					{Name: "gcp", PodIdentity: &v1alpha1.TriggerAuthPodIdentity{Provider: v1alpha1.PodIdentityProviderGCP}},
+					{Name: "none", PodIdentity: &v1alpha1.TriggerAuthPodIdentity{Provider: v1alpha1.PodIdentityProviderNone}},
+				},
+			},
+		},
+		{
+			name: "missing settings",
+			spec: v1alpha1.KedaSpec{
+				PodIdentity: &v1alpha1.PodIdentityCfg{Provider: v1alpha1.PodIdentityProviderAWSEKS},
+			},
+			problems: []string{"awsEks is required by the aws-eks provider"},
+		},
+		{
+			name: "settings of other providers",
+			spec: v1alpha1.KedaSpec{
+				PodIdentity: &v1alpha1.PodIdentityCfg{
+					Provider:      v1alpha1.PodIdentityProviderAzure,
+					Azure:         &v1alpha1.AzurePodIdentityCfg{Identity: "keda"},
+					AzureWorkload: &v1alpha1.AzureWorkloadIdentityCfg{ClientID: "0000-1111"},
+				},
+			},
+			problems: []string{"azureWorkload is not allowed with the azure provider"},
+		},
+		{
+			name: "trigger authentication of other provider",
+			spec: v1alpha1.KedaSpec{
+				PodIdentity: &v1alpha1.PodIdentityCfg{
+					Provider: v1alpha1.PodIdentityProviderAWSEKS,
+					AWSEKS:   &v1alpha1.AWSEKSIdentityCfg{RoleArn: "arn:aws:iam::111122223333:role/keda"},
+				},
+				TriggerAuthentications: []v1alpha1.TriggerAuthentication{
+					{Name: "kiam", PodIdentity: &v1alpha1.TriggerAuthPodIdentity{Provider: v1alpha1.PodIdentityProviderAWSKiam}},
+				},
+			},
+			problems: []string{"trigger authentication kiam uses the aws-kiam provider instead of aws-eks"},
+		},
+		{
+			name: "trigger authentication without pod identity of the operator",
+			spec: v1alpha1.KedaSpec{
+				TriggerAuthentications: []v1alpha1.TriggerAuthentication{
+					{Name: "gcp", PodIdentity: &v1alpha1.TriggerAuthPodIdentity{Provider: v1alpha1.PodIdentityProviderGCP}},
+					{Name: "none", PodIdentity: &v1alpha1.TriggerAuthPodIdentity{Provider: v1alpha1.PodIdentityProviderNone}},
+				},
+			},
+			problems: []string{"trigger authentication gcp uses the gcp provider instead of none"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.problems, validatePodIdentity(tt.spec))
+		})
+	}
+}
+
+func Test_sFnValidatePodIdentity(t *testing.T) {
+	t.Run("azure workload identity", func(t *testing.T) {
+		spec := v1alpha1.KedaSpec{
+			PodIdentity: &v1alpha1.PodIdentityCfg{
+				Provider: v1alpha1.PodIdentityProviderAzureWorkload,
+				AzureWorkload: &v1alpha1.AzureWorkloadIdentityCfg{
+					ClientID:        "0000-1111",
+					TenantID:        pointer.String("2222-3333"),
+					TokenExpiration: pointer.Int64(7200),
+				},
+			},
+		}
+		r := testChartFsm(t, spec)
+		s := &systemState{instance: v1alpha1.Keda{Spec: spec}}
+
+		fn, _, err := sFnValidatePodIdentity(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateMetricsServerDeployment), fnName(fn))
+
+		// the pod identity is rendered from the chart values
+		serviceAccount, err := r.firstUnstructed(func(u unstructured.Unstructured) bool {
+			return u.GetKind() == "ServiceAccount" && hasOperatorName(u)
+		})
+		require.NoError(t, err)
+		annotations := serviceAccount.GetAnnotations()
+		require.Equal(t, "0000-1111", annotations["azure.workload.identity/client-id"])
+		require.Equal(t, "2222-3333", annotations["azure.workload.identity/tenant-id"])
+		require.Equal(t, "7200", annotations["azure.workload.identity/service-account-token-expiration"])
+
+		deployment, err := r.kedaManagerDeployment()
+		require.NoError(t, err)
+		labels, _, err := unstructured.NestedStringMap(deployment.Object, "spec", "template", "metadata", "labels")
+		require.NoError(t, err)
+		require.Equal(t, "true", labels["azure.workload.identity/use"])
+		// selector labels are kept
+		require.Equal(t, operatorName, labels["app"])
+	})
+
+	t.Run("incompatible settings", func(t *testing.T) {
+		r := testManifestFsm(t)
+		s := &systemState{
+			instance: v1alpha1.Keda{
+				Spec: v1alpha1.KedaSpec{
+					PodIdentity: &v1alpha1.PodIdentityCfg{Provider: v1alpha1.PodIdentityProviderGCP},
+				},
+			},
+		}
+
+		_, _, err := sFnValidatePodIdentity(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+		require.Contains(t, s.instance.Status.Conditions[0].Message, "gcp is required by the gcp provider")
+	})
+
+	t.Run("trigger authentication without pod identity of the operator", func(t *testing.T) {
+		r := testManifestFsm(t)
+		s := &systemState{
+			instance: v1alpha1.Keda{
+				Spec: v1alpha1.KedaSpec{
+					TriggerAuthentications: []v1alpha1.TriggerAuthentication{
+						{Name: "eks", PodIdentity: &v1alpha1.TriggerAuthPodIdentity{Provider: v1alpha1.PodIdentityProviderAWSEKS}},
+					},
+				},
+			},
+		}
+
+		_, _, err := sFnValidatePodIdentity(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+		require.Contains(t, s.instance.Status.Conditions[0].Message, "trigger authentication eks uses the aws-eks provider instead of none")
+	})
+}
@@ -0,0 +1,86 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	psaapi "k8s.io/pod-security-admission/api"
+	"k8s.io/pod-security-admission/policy"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var (
+	ErrPodSecurity = errors.New("pod security violation")
+)
+
+// validatePodSecurity returns violations of the restricted Pod Security Standard
+func validatePodSecurity(deployment appsv1.Deployment) ([]string, error) {
+	evaluator, err := policy.NewEvaluator(policy.DefaultChecks())
+	if err != nil {
+		return nil, err
+	}
+
+	results := evaluator.EvaluatePod(
+		psaapi.LevelVersion{Level: psaapi.LevelRestricted, Version: psaapi.LatestVersion()},
+		&deployment.Spec.Template.ObjectMeta,
+		&deployment.Spec.Template.Spec,
+	)
+
+	var violations []string
+	for _, result := range results {
+		if result.Allowed {
+			continue
+		}
+		violations = append(violations, fmt.Sprintf("%s: %s (%s)",
+			deployment.Name, result.ForbiddenReason, result.ForbiddenDetail))
+	}
+	return violations, nil
+}
+
+// sFnValidatePodSecurity prevents applying keda pods which violate the
+// restricted Pod Security Standard
+func sFnValidatePodSecurity(_ context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	var violations []string
+	for _, p := range []predicate{isKedaOperatorDeployment, isKedaMatricsServerDeployment} {
+		u, err := r.firstUnstructed(p)
+		if err != nil {
+			return stopWithPodSecurityErr(s, err)
+		}
+
+		var deployment appsv1.Deployment
+		if err := fromUnstructured(u.Object, &deployment); err != nil {
+			return stopWithPodSecurityErr(s, err)
+		}
+
+		deploymentViolations, err := validatePodSecurity(deployment)
+		if err != nil {
+			return stopWithPodSecurityErr(s, err)
+		}
+		violations = append(violations, deploymentViolations...)
+	}
+
+	if len(violations) != 0 {
+		err := fmt.Errorf("%w: %s", ErrPodSecurity, strings.Join(violations, "; "))
+		r.log.With("err", err).Warn("keda pods are not applied")
+		s.instance.UpdateStateFromErr(
+			v1alpha1.ConditionTypeInstalled,
+			v1alpha1.ConditionReasonPodSecurityErr,
+			err,
+		)
+		return stopWithNoRequeue()
+	}
+	return switchState(sFnUpdateMonitoring)
+}
+
+func stopWithPodSecurityErr(s *systemState, err error) (stateFn, *ctrl.Result, error) {
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonPodSecurityErr,
+		err,
+	)
+	return stopWithErrorAnNoRequeue(err)
+}
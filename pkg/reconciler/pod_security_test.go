@@ -0,0 +1,107 @@
+package reconciler
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/helm"
+	"github.com/kyma-project/keda-manager/pkg/yaml"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+func testManifestFsm(t *testing.T) *fsm {
+	file, err := os.Open("../../keda-manager.yaml")
+	require.NoError(t, err)
+	defer file.Close()
+
+	objs, err := yaml.LoadData(file)
+	require.NoError(t, err)
+
+	return &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{Objs: objs},
+	}
+}
+
+func testChartFsm(t *testing.T, spec v1alpha1.KedaSpec) *fsm {
+	c, err := helm.Load("../../charts/keda")
+	require.NoError(t, err)
+
+	render := helm.NewRenderer(c, nil).Render
+	objs, err := render(spec, "kyma-system")
+	require.NoError(t, err)
+
+	return &fsm{
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{Objs: objs, Render: render},
+	}
+}
+
+func Test_sFnValidatePodSecurity(t *testing.T) {
+	t.Run("manifest is restricted", func(t *testing.T) {
+		r := testManifestFsm(t)
+		s := &systemState{}
+
+		fn, _, err := sFnValidatePodSecurity(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateMonitoring), fnName(fn))
+	})
+
+	t.Run("hardened pods are restricted", func(t *testing.T) {
+		r := testChartFsm(t, v1alpha1.KedaSpec{
+			Security: &v1alpha1.SecurityCfg{
+				RunAsUser:                    pointer.Int64(1000),
+				RunAsGroup:                   pointer.Int64(1000),
+				FSGroup:                      pointer.Int64(1000),
+				ReadOnlyRootFilesystem:       pointer.Bool(true),
+				SeccompProfile:               &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+				PriorityClassName:            pointer.String("keda"),
+				AutomountServiceAccountToken: pointer.Bool(false),
+			},
+		})
+
+		u, err := r.kedaMetricsServerDeployment()
+		require.NoError(t, err)
+
+		var deployment appsv1.Deployment
+		require.NoError(t, fromUnstructured(u.Object, &deployment))
+		podSpec := deployment.Spec.Template.Spec
+		require.Equal(t, "keda", podSpec.PriorityClassName)
+		require.Equal(t, int64(1000), *podSpec.SecurityContext.FSGroup)
+		// chart defaults are kept
+		require.True(t, *podSpec.SecurityContext.RunAsNonRoot)
+		require.False(t, *podSpec.AutomountServiceAccountToken)
+		require.Equal(t, corev1.SeccompProfileTypeRuntimeDefault, podSpec.SecurityContext.SeccompProfile.Type)
+		// the pod seccomp profile is not overridden by containers
+		require.Nil(t, podSpec.Containers[0].SecurityContext.SeccompProfile)
+		require.True(t, *podSpec.Containers[0].SecurityContext.ReadOnlyRootFilesystem)
+		// the metrics server writes to empty dirs
+		require.Len(t, podSpec.Volumes, 2)
+		require.Len(t, podSpec.Containers[0].VolumeMounts, 2)
+
+		s := &systemState{}
+		fn, _, err := sFnValidatePodSecurity(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateMonitoring), fnName(fn))
+	})
+
+	t.Run("violations are not applied", func(t *testing.T) {
+		r := testChartFsm(t, v1alpha1.KedaSpec{
+			Security: &v1alpha1.SecurityCfg{
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined},
+			},
+		})
+
+		s := &systemState{}
+		_, _, err := sFnValidatePodSecurity(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+		require.Contains(t, s.instance.Status.Conditions[0].Message, "keda-manager: seccompProfile")
+	})
+}
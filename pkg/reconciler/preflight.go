@@ -0,0 +1,214 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	metricsAPIGroup = "metrics.k8s.io"
+	kedaVersionKey  = "app.kubernetes.io/version"
+)
+
+type kubernetesVersions struct {
+	min, max string
+}
+
+var (
+	// kubernetes versions supported by KEDA versions, see
+	// https://keda.sh/docs/latest/operate/cluster/#kubernetes-compatibility
+	kedaKubernetesVersions = map[string]kubernetesVersions{
+		"2.8":  {min: "1.17", max: "1.25"},
+		"2.9":  {min: "1.23", max: "1.25"},
+		"2.10": {min: "1.24", max: "1.26"},
+		"2.11": {min: "1.25", max: "1.27"},
+		"2.12": {min: "1.26", max: "1.28"},
+	}
+)
+
+// preflightResult collects failed preflight checks; keda components are not
+// applied if any blocking check failed
+type preflightResult struct {
+	failures []string
+	blocking bool
+}
+
+func (p *preflightResult) fail(blocking bool, format string, args ...interface{}) {
+	p.failures = append(p.failures, fmt.Sprintf(format, args...))
+	p.blocking = p.blocking || blocking
+}
+
+// checkServerVersion warns if the kubernetes version is not supported by the bundled KEDA version
+func checkServerVersion(r *fsm, result *preflightResult) error {
+	deployment, err := r.kedaManagerDeployment()
+	if err != nil {
+		return err
+	}
+
+	kedaVersion, err := version.ParseGeneric(deployment.GetLabels()[kedaVersionKey])
+	if err != nil {
+		// versions of keda built from sources are not known
+		return nil
+	}
+
+	supported, found := kedaKubernetesVersions[fmt.Sprintf("%d.%d", kedaVersion.Major(), kedaVersion.Minor())]
+	if !found {
+		return nil
+	}
+
+	info, err := r.Discovery.ServerVersion()
+	if err != nil {
+		return err
+	}
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return err
+	}
+
+	// patch versions do not matter for compatibility
+	serverMinor := version.MustParseGeneric(fmt.Sprintf("%d.%d", serverVersion.Major(), serverVersion.Minor()))
+	if serverMinor.LessThan(version.MustParseGeneric(supported.min)) ||
+		version.MustParseGeneric(supported.max).LessThan(serverMinor) {
+		result.fail(false, "Kubernetes %s is not supported by KEDA %s, use Kubernetes v%s - v%s",
+			info.GitVersion, kedaVersion, supported.min, supported.max)
+	}
+	return nil
+}
+
+// checkAPIs makes sure all APIs keda components depend on are served; enabled
+// admission plugins are not exposed by the kubernetes API, so they are not checked
+func checkAPIs(r *fsm, result *preflightResult) error {
+	groups, err := r.Discovery.ServerGroups()
+	if err != nil {
+		return err
+	}
+
+	served := map[string]struct{}{}
+	for _, group := range groups.Groups {
+		served[group.Name] = struct{}{}
+		for _, groupVersion := range group.Versions {
+			served[groupVersion.GroupVersion] = struct{}{}
+		}
+	}
+
+	reported := map[string]struct{}{}
+	for _, obj := range r.Objs {
+		groupVersion := obj.GetAPIVersion()
+		if _, found := served[groupVersion]; found {
+			continue
+		}
+		if _, found := reported[groupVersion]; found {
+			continue
+		}
+		reported[groupVersion] = struct{}{}
+		result.fail(true, "%s API required by %s %s is not served, upgrade Kubernetes or enable the API",
+			groupVersion, obj.GetKind(), obj.GetName())
+	}
+
+	// the HPA uses resource metrics for cpu and memory triggers
+	if _, found := served[metricsAPIGroup]; !found {
+		result.fail(false, "%s API is not served, install metrics-server to scale on cpu and memory", metricsAPIGroup)
+	}
+	return nil
+}
+
+// checkAPIServices makes sure metrics APIs are not served by other metrics adapters
+func checkAPIServices(ctx context.Context, r *fsm, s *systemState, result *preflightResult) error {
+	for _, obj := range r.Objs {
+		if !isMetricsAPIService(obj) {
+			continue
+		}
+
+		var live unstructured.Unstructured
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		err := r.Get(ctx, types.NamespacedName{Name: obj.GetName()}, &live)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if live.GetLabels()["app.kubernetes.io/part-of"] == operatorName {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(live.Object, "spec", "service", "name")
+		namespace, _, _ := unstructured.NestedString(live.Object, "spec", "service", "namespace")
+		if name == matricsServerName && namespace == s.namespace {
+			continue
+		}
+		result.fail(true, "APIService %s is served by %s/%s, remove the other metrics adapter or its APIService",
+			obj.GetName(), namespace, name)
+	}
+	return nil
+}
+
+// runPreflightChecks runs all checks; failed checks are collected in the result
+func runPreflightChecks(ctx context.Context, r *fsm, s *systemState) (preflightResult, error) {
+	var result preflightResult
+	if err := checkServerVersion(r, &result); err != nil {
+		return result, err
+	}
+	if err := checkAPIs(r, &result); err != nil {
+		return result, err
+	}
+	err := checkAPIServices(ctx, r, s, &result)
+	return result, err
+}
+
+// sFnPreflight checks if keda components can work on the cluster before
+// they are applied; results are reported in the preflight condition
+func sFnPreflight(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	result, err := runPreflightChecks(ctx, r, s)
+	// the cluster is discovered again on the next reconciliation, so fixed
+	// blocking problems are noticed without waiting for the discovery cache
+	// to expire
+	if err != nil || result.blocking {
+		invalidateDiscovery(r.Discovery)
+	}
+	if err != nil {
+		return stopWithPreflightErr(s, err)
+	}
+
+	if len(result.failures) == 0 {
+		s.instance.UpdateCondition(
+			v1alpha1.ConditionTypePreflight,
+			metav1.ConditionTrue,
+			v1alpha1.ConditionReasonPreflightPassed,
+			"preflight checks passed",
+		)
+		return switchState(sFnUpdateKedaDeployment)
+	}
+
+	msg := strings.Join(result.failures, "; ")
+	s.instance.UpdateCondition(
+		v1alpha1.ConditionTypePreflight,
+		metav1.ConditionFalse,
+		v1alpha1.ConditionReasonPreflightFailed,
+		msg,
+	)
+	if result.blocking {
+		return stopWithPreflightErr(s, errors.New(msg))
+	}
+	return switchState(sFnUpdateKedaDeployment)
+}
+
+func stopWithPreflightErr(s *systemState, err error) (stateFn, *ctrl.Result, error) {
+	s.instance.UpdateStateFromErr(
+		v1alpha1.ConditionTypeInstalled,
+		v1alpha1.ConditionReasonPreflightFailed,
+		err,
+	)
+	return stopWithErrorAnNoRequeue(err)
+}
@@ -0,0 +1,118 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testPreflightFsm(t *testing.T, serverVersion string, groupVersions []string, objs ...client.Object) *fsm {
+	r := testManifestFsm(t)
+
+	var resources []*metav1.APIResourceList
+	for _, groupVersion := range groupVersions {
+		resources = append(resources, &metav1.APIResourceList{GroupVersion: groupVersion})
+	}
+	r.Discovery = &fakediscovery.FakeDiscovery{
+		Fake:               &clienttesting.Fake{Resources: resources},
+		FakedServerVersion: &version.Info{GitVersion: serverVersion},
+	}
+	r.Client = fake.NewClientBuilder().WithObjects(objs...).Build()
+	return r
+}
+
+var (
+	testServedAPIs = []string{
+		"v1",
+		"apps/v1",
+		"apiextensions.k8s.io/v1",
+		"apiregistration.k8s.io/v1",
+		"rbac.authorization.k8s.io/v1",
+		"metrics.k8s.io/v1beta1",
+	}
+)
+
+func Test_sFnPreflight(t *testing.T) {
+	t.Run("passed", func(t *testing.T) {
+		r := testPreflightFsm(t, "v1.25.4+k3s1", testServedAPIs)
+		s := &systemState{namespace: "kyma-system"}
+
+		fn, _, err := sFnPreflight(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateKedaDeployment), fnName(fn))
+
+		condition := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypePreflight))
+		require.NotNil(t, condition)
+		require.Equal(t, metav1.ConditionTrue, condition.Status)
+	})
+
+	t.Run("unsupported kubernetes version without metrics api", func(t *testing.T) {
+		r := testPreflightFsm(t, "v1.26.0", testServedAPIs[:len(testServedAPIs)-1])
+		s := &systemState{namespace: "kyma-system"}
+
+		// warnings do not stop the installation
+		fn, _, err := sFnPreflight(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnUpdateKedaDeployment), fnName(fn))
+
+		condition := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypePreflight))
+		require.NotNil(t, condition)
+		require.Equal(t, metav1.ConditionFalse, condition.Status)
+		require.Contains(t, condition.Message, "Kubernetes v1.26.0 is not supported by KEDA 2.8.0")
+		require.Contains(t, condition.Message, "install metrics-server")
+	})
+
+	t.Run("required api not served", func(t *testing.T) {
+		r := testPreflightFsm(t, "v1.25.0", testServedAPIs[1:])
+		discovery := NewCachedDiscovery(r.Discovery, time.Hour)
+		r.Discovery = discovery
+		s := &systemState{namespace: "kyma-system"}
+
+		_, _, err := sFnPreflight(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+		// the cluster is discovered again on the next reconciliation
+		require.False(t, discovery.Fresh())
+
+		condition := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypePreflight))
+		require.NotNil(t, condition)
+		require.Contains(t, condition.Message, "v1 API required by ServiceAccount keda-manager is not served")
+	})
+
+	t.Run("apiservice served by other adapter", func(t *testing.T) {
+		apiService := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apiregistration.k8s.io/v1",
+			"kind":       "APIService",
+			"metadata": map[string]interface{}{
+				"name": "v1beta1.external.metrics.k8s.io",
+			},
+			"spec": map[string]interface{}{
+				"service": map[string]interface{}{
+					"name":      "prometheus-adapter",
+					"namespace": "monitoring",
+				},
+			},
+		}}
+		r := testPreflightFsm(t, "v1.25.0", testServedAPIs, apiService)
+		s := &systemState{namespace: "kyma-system"}
+
+		_, _, err := sFnPreflight(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+
+		condition := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypePreflight))
+		require.NotNil(t, condition)
+		require.Contains(t, condition.Message, "served by monitoring/prometheus-adapter")
+	})
+}
@@ -3,6 +3,8 @@ package reconciler
 import (
 	"context"
 
+	"github.com/kyma-project/keda-manager/pkg/metrics"
+
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -16,6 +18,9 @@ func sFnRemoveFinalizer(ctx context.Context, r *fsm, s *systemState) (stateFn, *
 		return nil, &ctrl.Result{Requeue: true}, err
 	}
 
+	metrics.DeleteState(s.instance.Namespace, s.instance.Name)
+	metrics.DeleteManagedObjects(s.instance.Namespace, s.instance.Name)
+	s.events.finalizerRemoved = true
 	r.log.Debug("finalizer removed")
 	return nil, nil, nil
 }
@@ -0,0 +1,70 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var (
+	ErrChartRequired = errors.New("keda components are not rendered from the chart")
+)
+
+// chartFields returns fields of the spec which are applied as chart values
+// only, so they cannot be applied to objects loaded from a manifest
+func chartFields(spec v1alpha1.KedaSpec) []string {
+	var fields []string
+	if spec.Monitoring != nil && spec.Monitoring.Enabled {
+		fields = append(fields, "monitoring")
+	}
+	if spec.PodIdentity != nil {
+		fields = append(fields, "podIdentity")
+	}
+	if spec.Security != nil {
+		fields = append(fields, "security")
+	}
+	return fields
+}
+
+// sFnRender renders module component parts with values of the instance spec
+// directly into the target namespace
+func sFnRender(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	if r.Render == nil {
+		if fields := chartFields(s.instance.Spec); len(fields) != 0 {
+			err := fmt.Errorf("%w: %s cannot be applied", ErrChartRequired, strings.Join(fields, ", "))
+			r.log.With("err", err).Warn("keda components are not applied")
+			s.instance.UpdateStateFromErr(
+				v1alpha1.ConditionTypeInstalled,
+				v1alpha1.ConditionReasonRenderErr,
+				err,
+			)
+			return stopWithNoRequeue()
+		}
+		return switchState(sFnPreflight)
+	}
+
+	spec, err := withMonitoringMode(ctx, r, s.instance.Spec)
+	if err != nil {
+		return stopWithMonitoringErr(s, err)
+	}
+
+	objs, err := r.Render(spec, s.namespace)
+	if err == nil {
+		err = ValidateObjs(objs)
+	}
+	if err != nil {
+		s.instance.UpdateStateFromErr(
+			v1alpha1.ConditionTypeInstalled,
+			v1alpha1.ConditionReasonRenderErr,
+			err,
+		)
+		return stopWithErrorAnNoRequeue(err)
+	}
+
+	r.Objs = objs
+	return switchState(sFnPreflight)
+}
@@ -0,0 +1,94 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_sFnRender(t *testing.T) {
+	t.Run("render objects with the instance spec", func(t *testing.T) {
+		var rendered v1alpha1.KedaSpec
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{
+				Render: func(spec v1alpha1.KedaSpec, namespace string) ([]unstructured.Unstructured, error) {
+					rendered = spec
+					operator := testValidateObj("apps/v1", "Deployment", operatorName)
+					metricsServer := testValidateObj("apps/v1", "Deployment", matricsServerName)
+					operator.SetNamespace(namespace)
+					metricsServer.SetNamespace(namespace)
+					return []unstructured.Unstructured{operator, metricsServer}, nil
+				},
+			},
+		}
+		s := &systemState{
+			namespace: "keda",
+			instance: v1alpha1.Keda{
+				Spec: v1alpha1.KedaSpec{Env: v1alpha1.EnvVars{{Name: "test", Value: "test"}}},
+			},
+		}
+
+		fn, _, err := sFnRender(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnPreflight), fnName(fn))
+		require.Equal(t, s.instance.Spec, rendered)
+		require.Len(t, r.Objs, 2)
+		require.Equal(t, "keda", r.Objs[0].GetNamespace())
+	})
+
+	t.Run("loaded objects", func(t *testing.T) {
+		objs := []unstructured.Unstructured{testValidateObj("apps/v1", "Deployment", operatorName)}
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{Objs: objs},
+		}
+
+		fn, _, err := sFnRender(context.Background(), r, &systemState{})
+		require.NoError(t, err)
+		require.Equal(t, fnName(sFnPreflight), fnName(fn))
+		require.Equal(t, objs, r.Objs)
+	})
+
+	t.Run("chart values of loaded objects", func(t *testing.T) {
+		objs := []unstructured.Unstructured{testValidateObj("apps/v1", "Deployment", operatorName)}
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{Objs: objs},
+		}
+		s := &systemState{
+			instance: v1alpha1.Keda{
+				Spec: v1alpha1.KedaSpec{Security: &v1alpha1.SecurityCfg{}},
+			},
+		}
+
+		_, _, err := sFnRender(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+		require.Equal(t, string(v1alpha1.ConditionReasonRenderErr), s.instance.Status.Conditions[0].Reason)
+		require.Contains(t, s.instance.Status.Conditions[0].Message, "security")
+	})
+
+	t.Run("render error", func(t *testing.T) {
+		r := &fsm{
+			log: zap.NewNop().Sugar(),
+			Cfg: Cfg{
+				Render: func(v1alpha1.KedaSpec, string) ([]unstructured.Unstructured, error) {
+					return nil, errors.New("template error")
+				},
+			},
+		}
+		s := &systemState{}
+
+		_, _, err := sFnRender(context.Background(), r, s)
+		require.NoError(t, err)
+		require.Nil(t, r.Objs)
+		require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+		require.Equal(t, string(v1alpha1.ConditionReasonRenderErr), s.instance.Status.Conditions[0].Reason)
+	})
+}
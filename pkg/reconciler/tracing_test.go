@@ -0,0 +1,76 @@
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var errTracingTest = errors.New("test error")
+
+func sFnTracingTestApply(ctx context.Context, r *fsm, _ *systemState) (stateFn, *ctrl.Result, error) {
+	trace.SpanFromContext(ctx).AddEvent("apply", objAttributes(r.Objs[0]))
+	return switchState(sFnTracingTestFail)
+}
+
+func sFnTracingTestFail(_ context.Context, _ *fsm, _ *systemState) (stateFn, *ctrl.Result, error) {
+	return nil, nil, errTracingTest
+}
+
+func Test_fsm_Run_tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	m := &fsm{
+		fn:  sFnTracingTestApply,
+		log: zap.NewNop().Sugar(),
+		Cfg: Cfg{
+			Objs: testNamespaceObjs(),
+		},
+	}
+	instance := v1alpha1.Keda{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "default",
+			Namespace:  "kyma-system",
+			Generation: 3,
+		},
+	}
+
+	_, err := m.Run(context.Background(), instance)
+	require.ErrorIs(t, err, errTracingTest)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+
+	apply, fail, reconcile := spans[0], spans[1], spans[2]
+	require.Equal(t, "sFnTracingTestApply", apply.Name)
+	require.Equal(t, "sFnTracingTestFail", fail.Name)
+	require.Equal(t, "reconcile", reconcile.Name)
+
+	// state spans are children of the reconcile span
+	require.Equal(t, reconcile.SpanContext.SpanID(), apply.Parent.SpanID())
+	require.Equal(t, reconcile.SpanContext.SpanID(), fail.Parent.SpanID())
+
+	require.Contains(t, reconcile.Attributes, attribute.String("keda.name", "default"))
+	require.Contains(t, reconcile.Attributes, attribute.Int64("keda.generation", 3))
+	require.Equal(t, codes.Error, reconcile.Status.Code)
+	require.Equal(t, codes.Error, fail.Status.Code)
+
+	require.Len(t, apply.Events, 1)
+	require.Contains(t, apply.Events[0].Attributes, attribute.String("object.gvk", "apps/v1, Kind=Deployment"))
+}
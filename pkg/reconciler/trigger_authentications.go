@@ -0,0 +1,144 @@
+package reconciler
+
+import (
+	"context"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	kedaAPIVersion = "keda.sh/v1alpha1"
+
+	clusterTriggerAuthenticationKind = "ClusterTriggerAuthentication"
+)
+
+var (
+	// managed trigger authentications are distinguished from the ones
+	// created by users with these labels
+	triggerAuthenticationLabels = map[string]string{
+		"app.kubernetes.io/part-of":    operatorName,
+		"app.kubernetes.io/managed-by": operatorName,
+	}
+)
+
+func triggerAuthenticationObj(name string) unstructured.Unstructured {
+	var u unstructured.Unstructured
+	u.SetAPIVersion(kedaAPIVersion)
+	u.SetKind(clusterTriggerAuthenticationKind)
+	u.SetName(name)
+	return u
+}
+
+// triggerAuthenticationObjs returns ClusterTriggerAuthentications declared in the instance spec
+func triggerAuthenticationObjs(auths []v1alpha1.TriggerAuthentication) []unstructured.Unstructured {
+	result := make([]unstructured.Unstructured, 0, len(auths))
+	for _, auth := range auths {
+		spec := map[string]interface{}{}
+
+		if len(auth.SecretTargetRef) > 0 {
+			refs := make([]interface{}, 0, len(auth.SecretTargetRef))
+			for _, ref := range auth.SecretTargetRef {
+				refs = append(refs, map[string]interface{}{
+					"parameter": ref.Parameter,
+					"name":      ref.Name,
+					"key":       ref.Key,
+				})
+			}
+			spec["secretTargetRef"] = refs
+		}
+
+		if len(auth.Env) > 0 {
+			envs := make([]interface{}, 0, len(auth.Env))
+			for _, env := range auth.Env {
+				value := map[string]interface{}{
+					"parameter": env.Parameter,
+					"name":      env.Name,
+				}
+				if env.ContainerName != nil {
+					value["containerName"] = *env.ContainerName
+				}
+				envs = append(envs, value)
+			}
+			spec["env"] = envs
+		}
+
+		if auth.PodIdentity != nil {
+			podIdentity := map[string]interface{}{
+				"provider": string(auth.PodIdentity.Provider),
+			}
+			if auth.PodIdentity.IdentityID != nil {
+				podIdentity["identityId"] = *auth.PodIdentity.IdentityID
+			}
+			spec["podIdentity"] = podIdentity
+		}
+
+		u := triggerAuthenticationObj(auth.Name)
+		u.SetLabels(triggerAuthenticationLabels)
+		u.Object["spec"] = spec
+		result = append(result, u)
+	}
+	return result
+}
+
+// managedTriggerAuthentications lists ClusterTriggerAuthentications created by the manager
+func managedTriggerAuthentications(ctx context.Context, c client.Client) ([]unstructured.Unstructured, error) {
+	var list unstructured.UnstructuredList
+	list.SetAPIVersion(kedaAPIVersion)
+	list.SetKind(clusterTriggerAuthenticationKind + "List")
+
+	err := c.List(ctx, &list, client.MatchingLabels(triggerAuthenticationLabels))
+	// keda CRDs are not installed yet
+	if meta.IsNoMatchError(err) {
+		return nil, nil
+	}
+	return list.Items, err
+}
+
+// pruneTriggerAuthentications removes managed ClusterTriggerAuthentications
+// which are not desired anymore; user objects are left untouched
+func pruneTriggerAuthentications(ctx context.Context, r *fsm, s *systemState, desired []unstructured.Unstructured, reason string) error {
+	live, err := managedTriggerAuthentications(ctx, r.Client)
+	if err != nil {
+		return err
+	}
+
+	names := map[string]struct{}{}
+	for _, obj := range desired {
+		names[obj.GetName()] = struct{}{}
+	}
+
+	var obsolete []unstructured.Unstructured
+	for _, obj := range live {
+		if _, found := names[obj.GetName()]; !found {
+			obsolete = append(obsolete, obj)
+		}
+	}
+	return deleteGeneratedObjs(ctx, r, s, obsolete, reason)
+}
+
+// sFnUpdateTriggerAuthentications keeps managed ClusterTriggerAuthentications
+// in sync with the instance spec
+func sFnUpdateTriggerAuthentications(ctx context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
+	desired := triggerAuthenticationObjs(s.instance.Spec.TriggerAuthentications)
+	// trigger authentications are applied together with keda components
+	r.Objs = append(r.Objs, desired...)
+
+	// dry run must not mutate the cluster
+	if r.isDryRun(&s.instance) {
+		return switchState(sFnEnsureNamespace)
+	}
+
+	if err := pruneTriggerAuthentications(ctx, r, s, desired, EventReasonPruned); err != nil {
+		s.instance.UpdateStateFromErr(
+			v1alpha1.ConditionTypeInstalled,
+			v1alpha1.ConditionReasonTriggerAuthErr,
+			err,
+		)
+		return stopWithErrorAnNoRequeue(err)
+	}
+	return switchState(sFnEnsureNamespace)
+}
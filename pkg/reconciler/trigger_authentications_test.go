@@ -0,0 +1,92 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_triggerAuthenticationObjs(t *testing.T) {
+	objs := triggerAuthenticationObjs([]v1alpha1.TriggerAuthentication{
+		{
+			Name: "rabbitmq",
+			SecretTargetRef: []v1alpha1.TriggerAuthSecretTargetRef{
+				{Parameter: "host", Name: "rabbitmq-conn", Key: "host"},
+			},
+			Env: []v1alpha1.TriggerAuthEnv{
+				{Parameter: "user", Name: "RABBITMQ_USER", ContainerName: pointerTo("app")},
+			},
+		},
+		{
+			Name: "azure",
+			PodIdentity: &v1alpha1.TriggerAuthPodIdentity{
+				Provider:   v1alpha1.PodIdentityProviderAzureWorkload,
+				IdentityID: pointerTo("0000-1111"),
+			},
+		},
+	})
+	require.Len(t, objs, 2)
+
+	require.Equal(t, clusterTriggerAuthenticationKind, objs[0].GetKind())
+	require.Equal(t, triggerAuthenticationLabels, objs[0].GetLabels())
+	refs, _, err := unstructured.NestedSlice(objs[0].Object, "spec", "secretTargetRef")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"parameter": "host", "name": "rabbitmq-conn", "key": "host"},
+	}, refs)
+	envs, _, err := unstructured.NestedSlice(objs[0].Object, "spec", "env")
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"parameter": "user", "name": "RABBITMQ_USER", "containerName": "app"},
+	}, envs)
+	_, found, err := unstructured.NestedMap(objs[0].Object, "spec", "podIdentity")
+	require.NoError(t, err)
+	require.False(t, found)
+
+	provider, _, err := unstructured.NestedString(objs[1].Object, "spec", "podIdentity", "provider")
+	require.NoError(t, err)
+	require.Equal(t, "azure-workload", provider)
+	identityID, _, err := unstructured.NestedString(objs[1].Object, "spec", "podIdentity", "identityId")
+	require.NoError(t, err)
+	require.Equal(t, "0000-1111", identityID)
+}
+
+func Test_sFnUpdateTriggerAuthentications(t *testing.T) {
+	managed := triggerAuthenticationObjs([]v1alpha1.TriggerAuthentication{{Name: "obsolete"}})[0]
+	user := triggerAuthenticationObj("user")
+
+	c := fake.NewClientBuilder().WithObjects(&managed, &user).Build()
+	r := &fsm{
+		log: zap.NewNop().Sugar(),
+		K8s: K8s{Client: c},
+	}
+	s := &systemState{
+		namespace: "kyma-system",
+		instance: v1alpha1.Keda{
+			Spec: v1alpha1.KedaSpec{
+				TriggerAuthentications: []v1alpha1.TriggerAuthentication{{Name: "desired"}},
+			},
+		},
+	}
+
+	fn, _, err := sFnUpdateTriggerAuthentications(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, fnName(sFnEnsureNamespace), fnName(fn))
+	require.Len(t, r.Objs, 1)
+	require.Equal(t, "desired", r.Objs[0].GetName())
+
+	// obsolete managed trigger authentications are pruned
+	err = c.Get(context.Background(), client.ObjectKeyFromObject(&managed), &managed)
+	require.Error(t, err)
+	require.NoError(t, client.IgnoreNotFound(err))
+	require.Len(t, s.events.actions[EventReasonPruned], 1)
+
+	// trigger authentications created by users are left untouched
+	require.NoError(t, c.Get(context.Background(), client.ObjectKeyFromObject(&user), &user))
+}
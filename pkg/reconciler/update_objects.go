@@ -97,16 +97,33 @@ func envVars(k *v1alpha1.Keda) *v1alpha1.EnvVars {
 	return nil
 }
 
+// metricsSvrEnvVars returns env of the metrics server; the annotation with
+// the metrics server env is validated before the metrics server is updated
+func metricsSvrEnvVars(k *v1alpha1.Keda) *v1alpha1.EnvVars {
+	if k == nil {
+		return nil
+	}
+	env, err := k.MetricsServerEnv()
+	if err != nil {
+		return nil
+	}
+	return &env
+}
+
 func buildSfnUpdateOperatorEnvs(u *unstructured.Unstructured) stateFn {
-	return buildSfnUpdateObject(u, updateKedaContanierEnvs, envVars, sFnUpdateMetricsServerDeployment)
+	return buildSfnUpdateObject(u, updateKedaContanierEnvs, envVars, sFnValidatePodIdentity)
 }
 
 func buildSfnUpdateMetricsSvrEnvVars(u *unstructured.Unstructured) stateFn {
-	return buildSfnUpdateObject(u, updateKedaContanierEnvs, envVars, sFnApply)
+	return buildSfnUpdateObject(u, updateKedaContanierEnvs, metricsSvrEnvVars, sFnUpdateNetwork)
 }
 
 func sFnUpdateMetricsServerDeployment(_ context.Context, r *fsm, s *systemState) (stateFn, *ctrl.Result, error) {
 	u, err := r.kedaMetricsServerDeployment()
+	if err == nil {
+		// the metrics server must not fall back to the operator env
+		_, err = s.instance.MetricsServerEnv()
+	}
 	if err != nil {
 		s.instance.UpdateStateFromErr(
 			v1alpha1.ConditionTypeInstalled,
@@ -0,0 +1,31 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_sFnUpdateMetricsServerDeployment_invalidEnv(t *testing.T) {
+	r := testManifestFsm(t)
+	s := &systemState{
+		instance: v1alpha1.Keda{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1alpha1.MetricsServerEnvAnnotation:         "invalid",
+					v1alpha1.MetricsServerEnvChecksumAnnotation: v1alpha1.EnvVars(nil).Checksum(),
+				},
+			},
+		},
+	}
+
+	// the metrics server does not fall back to the operator env
+	_, _, err := sFnUpdateMetricsServerDeployment(context.Background(), r, s)
+	require.NoError(t, err)
+	require.Equal(t, v1alpha1.StateError, s.instance.Status.State)
+	require.Equal(t, string(v1alpha1.ConditionReasonDeploymentUpdateErr), s.instance.Status.Conditions[0].Reason)
+	require.Contains(t, s.instance.Status.Conditions[0].Message, v1alpha1.MetricsServerEnvAnnotation)
+}
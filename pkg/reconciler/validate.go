@@ -0,0 +1,56 @@
+package reconciler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var (
+	ErrInvalidObjs = errors.New("invalid objects")
+)
+
+// ValidateObjs checks if module component parts can be managed by the reconciler
+func ValidateObjs(objs []unstructured.Unstructured) error {
+	if len(objs) == 0 {
+		return fmt.Errorf("%w: no objects found", ErrInvalidObjs)
+	}
+
+	var problems []string
+	visited := map[string]int{}
+	for i, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if gvk.Kind == "" || gvk.Version == "" {
+			problems = append(problems, fmt.Sprintf("object %d has no apiVersion or kind", i))
+			continue
+		}
+
+		if obj.GetName() == "" {
+			problems = append(problems, fmt.Sprintf("object %d (%s) has no name", i, gvk))
+			continue
+		}
+
+		key := fmt.Sprintf("%s %s/%s", gvk.GroupKind(), obj.GetNamespace(), obj.GetName())
+		if j, found := visited[key]; found {
+			problems = append(problems, fmt.Sprintf("objects %d and %d are both %s", j, i, key))
+			continue
+		}
+		visited[key] = i
+	}
+
+	cfg := Cfg{Objs: objs}
+	if _, err := cfg.kedaManagerDeployment(); err != nil {
+		problems = append(problems, fmt.Sprintf("%s deployment not found", operatorName))
+	}
+
+	if _, err := cfg.kedaMetricsServerDeployment(); err != nil {
+		problems = append(problems, fmt.Sprintf("%s deployment not found", matricsServerName))
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidObjs, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package reconciler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testValidateObj(apiVersion, kind, name string) unstructured.Unstructured {
+	var u unstructured.Unstructured
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetName(name)
+	u.SetNamespace("kyma-system")
+	return u
+}
+
+func TestValidateObjs(t *testing.T) {
+	operator := testValidateObj("apps/v1", "Deployment", operatorName)
+	metricsServer := testValidateObj("apps/v1", "Deployment", matricsServerName)
+
+	tests := []struct {
+		name    string
+		objs    []unstructured.Unstructured
+		wantErr string
+	}{
+		{
+			name: "valid",
+			objs: []unstructured.Unstructured{
+				operator,
+				metricsServer,
+				testValidateObj("v1", "ServiceAccount", operatorName),
+			},
+		},
+		{
+			name:    "empty",
+			wantErr: "no objects found",
+		},
+		{
+			name: "no gvk",
+			objs: []unstructured.Unstructured{
+				operator,
+				metricsServer,
+				testValidateObj("", "", "test"),
+			},
+			wantErr: "object 2 has no apiVersion or kind",
+		},
+		{
+			name: "no name",
+			objs: []unstructured.Unstructured{
+				operator,
+				metricsServer,
+				testValidateObj("v1", "Service", ""),
+			},
+			wantErr: "object 2 (/v1, Kind=Service) has no name",
+		},
+		{
+			name: "duplicated",
+			objs: []unstructured.Unstructured{
+				operator,
+				metricsServer,
+				operator,
+			},
+			wantErr: "objects 0 and 2 are both Deployment.apps kyma-system/keda-manager",
+		},
+		{
+			name: "missing deployments",
+			objs: []unstructured.Unstructured{
+				testValidateObj("v1", "ServiceAccount", operatorName),
+			},
+			wantErr: "keda-manager deployment not found; keda-manager-metrics-apiserver deployment not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateObjs(tt.objs)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidObjs)
+			require.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -2,9 +2,15 @@ package reconciler
 
 import (
 	"context"
+	"time"
+
 	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/kyma-project/keda-manager/pkg/metrics"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
@@ -41,10 +47,33 @@ func sFnVerify(_ context.Context, _ *fsm, s *systemState) (stateFn, *ctrl.Result
 		return stopWithNoRequeue()
 	}
 
+	// network policies must let the kube-apiserver reach the metrics server
+	if isNetworkPolicyEnabled(&s.instance) && !isMetricsAPIServiceAvailable(s.objs) {
+		s.instance.UpdateStateProcessing(
+			v1alpha1.ConditionTypeInstalled,
+			v1alpha1.ConditionReasonVerification,
+			"waiting for the metrics server to be reachable",
+		)
+		return stopWithNoRequeue()
+	}
+
+	// the observed generation is reported once the spec is applied and ready
+	s.instance.Status.ObservedGeneration = s.instance.Generation
+
 	if s.instance.Status.State == "Ready" {
+		// status changes made during reconciliation still have to be saved
+		if !equality.Semantic.DeepEqual(s.instance.Status, s.snapshot) {
+			return stopWithNoRequeue()
+		}
 		return nil, nil, nil
 	}
 
+	// the installed condition is unknown since the installation started
+	installed := meta.FindStatusCondition(s.instance.Status.Conditions, string(v1alpha1.ConditionTypeInstalled))
+	if installed != nil && installed.Status == metav1.ConditionUnknown {
+		metrics.ObserveVerifyLatency(time.Since(installed.LastTransitionTime.Time))
+	}
+
 	s.instance.UpdateStateReady(
 		v1alpha1.ConditionTypeInstalled,
 		v1alpha1.ConditionReasonVerified,
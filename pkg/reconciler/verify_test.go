@@ -0,0 +1,56 @@
+package reconciler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/keda-manager/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testAvailableDeployment(t *testing.T, name string) unstructured.Unstructured {
+	deployment := appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kyma-system"},
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+	obj, err := toUnstructed(&deployment)
+	require.NoError(t, err)
+	return unstructured.Unstructured{Object: obj}
+}
+
+func Test_sFnVerify_observedGeneration(t *testing.T) {
+	instance := v1alpha1.Keda{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+
+	t.Run("not ready", func(t *testing.T) {
+		s := &systemState{instance: *instance.DeepCopy()}
+
+		_, _, err := sFnVerify(context.Background(), nil, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateProcessing, s.instance.Status.State)
+		require.Zero(t, s.instance.Status.ObservedGeneration)
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		s := &systemState{
+			instance: *instance.DeepCopy(),
+			objs: []unstructured.Unstructured{
+				testAvailableDeployment(t, operatorName),
+				testAvailableDeployment(t, matricsServerName),
+			},
+		}
+
+		_, _, err := sFnVerify(context.Background(), nil, s)
+		require.NoError(t, err)
+		require.Equal(t, v1alpha1.StateReady, s.instance.Status.State)
+		require.Equal(t, int64(2), s.instance.Status.ObservedGeneration)
+	})
+}
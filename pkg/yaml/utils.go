@@ -1,35 +1,161 @@
 package yaml
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"sort"
+	"strings"
 
-	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 )
 
+var (
+	ErrInvalidObject = errors.New("invalid object")
+
+	// InstallOrder is the order in which objects of given kinds are installed;
+	// objects of other kinds are installed at the end
+	InstallOrder = []string{
+		"Namespace",
+		"CustomResourceDefinition",
+		"NetworkPolicy",
+		"ResourceQuota",
+		"LimitRange",
+		"PodDisruptionBudget",
+		"ServiceAccount",
+		"ClusterRole",
+		"ClusterRoleBinding",
+		"Role",
+		"RoleBinding",
+		"Secret",
+		"ConfigMap",
+		"Service",
+		"DaemonSet",
+		"Pod",
+		"ReplicaSet",
+		"Deployment",
+		"HorizontalPodAutoscaler",
+		"StatefulSet",
+		"Job",
+		"CronJob",
+		"APIService",
+	}
+
+	installPriority = func() map[string]int {
+		result := make(map[string]int, len(InstallOrder))
+		for i, kind := range InstallOrder {
+			result[kind] = i
+		}
+		return result
+	}()
+)
+
+// InstallPriority returns the position of given kind in the install order
+func InstallPriority(kind string) int {
+	if priority, found := installPriority[kind]; found {
+		return priority
+	}
+	return len(InstallOrder)
+}
+
+// LoadData decodes all yaml or json documents from given reader; List kinds
+// are flattened and the returned objects are sorted by install order
 func LoadData(r io.Reader) ([]unstructured.Unstructured, error) {
 	results := make([]unstructured.Unstructured, 0)
-	decoder := yaml.NewDecoder(r)
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
 
-	for {
-		var obj map[string]interface{}
-		err := decoder.Decode(&obj)
+	for i := 0; ; i++ {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
 
 		if err == io.EOF {
 			break
 		}
 
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("document %d: %w", i, err)
 		}
 
-		u := unstructured.Unstructured{Object: obj}
-		if u.GetObjectKind().GroupVersionKind().Kind == "CustomResourceDefinition" {
-			results = append([]unstructured.Unstructured{u}, results...)
-			continue
+		objs, err := decodeDocument(raw)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
 		}
-		results = append(results, u)
+		results = append(results, objs...)
+	}
+
+	SortByInstallOrder(results)
+	return results, nil
+}
+
+func decodeDocument(raw []byte) ([]unstructured.Unstructured, error) {
+	raw = bytes.TrimSpace(raw)
+	// skip empty documents
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	var obj map[string]interface{}
+	if err := utiljson.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+
+	u := unstructured.Unstructured{Object: obj}
+	if !isList(u) {
+		return []unstructured.Unstructured{u}, validate(u)
+	}
+
+	items, _, err := unstructured.NestedSlice(obj, "items")
+	if err != nil {
+		return nil, err
 	}
 
+	results := make([]unstructured.Unstructured, 0, len(items))
+	for i, item := range items {
+		itemObj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: list item %d is not an object", ErrInvalidObject, i)
+		}
+
+		itemU := unstructured.Unstructured{Object: itemObj}
+		if err := validate(itemU); err != nil {
+			return nil, fmt.Errorf("list item %d: %w", i, err)
+		}
+		results = append(results, itemU)
+	}
 	return results, nil
 }
+
+func isList(u unstructured.Unstructured) bool {
+	_, found := u.Object["items"]
+	return found && strings.HasSuffix(u.GetKind(), "List")
+}
+
+func validate(u unstructured.Unstructured) error {
+	var missing []string
+	if u.GetAPIVersion() == "" {
+		missing = append(missing, "apiVersion")
+	}
+	if u.GetKind() == "" {
+		missing = append(missing, "kind")
+	}
+	if u.GetName() == "" {
+		missing = append(missing, "metadata.name")
+	}
+
+	if len(missing) != 0 {
+		return fmt.Errorf("%w: %s not set", ErrInvalidObject, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// SortByInstallOrder sorts objects by install order of their kinds keeping
+// the original order of objects of the same kind
+func SortByInstallOrder(objs []unstructured.Unstructured) {
+	sort.SliceStable(objs, func(i, j int) bool {
+		return InstallPriority(objs[i].GetKind()) < InstallPriority(objs[j].GetKind())
+	})
+}
@@ -0,0 +1,200 @@
+package yaml
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func kinds(objs []unstructured.Unstructured) []string {
+	result := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		result = append(result, obj.GetKind())
+	}
+	return result
+}
+
+func TestLoadData(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantKinds []string
+		wantErr   string
+	}{
+		{
+			name: "skip empty documents",
+			data: `---
+# comment only
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+---
+---
+`,
+			wantKinds: []string{"ConfigMap"},
+		},
+		{
+			name:      "json",
+			data:      `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "test"}}`,
+			wantKinds: []string{"Service"},
+		},
+		{
+			name: "json stream",
+			data: `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "test"}}
+{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "test"}}`,
+			wantKinds: []string{"Namespace", "Service"},
+		},
+		{
+			name: "list",
+			data: `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: test
+- apiVersion: v1
+  kind: ServiceAccount
+  metadata:
+    name: test
+`,
+			wantKinds: []string{"ServiceAccount", "Service"},
+		},
+		{
+			name: "install order",
+			data: `apiVersion: apiregistration.k8s.io/v1
+kind: APIService
+metadata:
+  name: test
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: test
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: test
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: test
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: test
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: test
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: test
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+`,
+			wantKinds: []string{
+				"Namespace",
+				"CustomResourceDefinition",
+				"ServiceAccount",
+				"ClusterRole",
+				"RoleBinding",
+				"Secret",
+				"Service",
+				"Deployment",
+				"APIService",
+				"Unknown",
+			},
+		},
+		{
+			name: "no apiVersion",
+			data: `kind: ConfigMap
+metadata:
+  name: test
+`,
+			wantErr: "document 0: invalid object: apiVersion not set",
+		},
+		{
+			name: "no kind and name",
+			data: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+---
+apiVersion: v1
+metadata:
+  labels:
+    test: test
+`,
+			wantErr: "document 1: invalid object: kind, metadata.name not set",
+		},
+		{
+			name: "invalid list item",
+			data: `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+`,
+			wantErr: "document 0: list item 0: invalid object: metadata.name not set",
+		},
+		{
+			name:    "malformed",
+			data:    "apiVersion: v1\nkind: [",
+			wantErr: "document 0:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := LoadData(strings.NewReader(tt.data))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantKinds, kinds(objs))
+		})
+	}
+}
+
+func TestLoadData_manifest(t *testing.T) {
+	file, err := os.Open("../../keda-manager.yaml")
+	require.NoError(t, err)
+	defer file.Close()
+
+	objs, err := LoadData(file)
+	require.NoError(t, err)
+	require.Len(t, objs, 15)
+	require.Equal(t, "CustomResourceDefinition", objs[0].GetKind())
+	require.Equal(t, "APIService", objs[len(objs)-1].GetKind())
+
+	// decoded objects can be deep copied
+	for _, obj := range objs {
+		require.Equal(t, obj, *obj.DeepCopy())
+	}
+}